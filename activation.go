@@ -0,0 +1,164 @@
+/*
+ * Copyright 2018 Farsight Security, Inc.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package config
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Addresses in the "systemd" and "fd" networks refer to sockets inherited
+// from the parent process rather than addresses to listen on.
+//
+// An address "systemd:name" refers to the sockets passed by systemd socket
+// activation with the FileDescriptorName (or socket unit name) "name", as
+// given in the LISTEN_FDS and LISTEN_FDNAMES environment variables.
+//
+// An address "fd:N" refers to the inherited file descriptor N.
+const (
+	systemdNetwork = "systemd"
+	fdNetwork      = "fd"
+)
+
+// listenFDsStart is the first file descriptor passed by systemd.
+const listenFDsStart = 3
+
+var activation struct {
+	once  sync.Once
+	files map[string][]*os.File
+}
+
+// inheritedFDs holds the files created for "fd:N" addresses, so that they
+// are not closed when garbage collected.
+var inheritedFDs struct {
+	sync.Mutex
+	files map[int]*os.File
+}
+
+type errNoActivationSocket string
+
+func (e errNoActivationSocket) Error() string {
+	return fmt.Sprintf("No socket named '%s' passed by systemd",
+		string(e))
+}
+
+type errInvalidFD string
+
+func (e errInvalidFD) Error() string {
+	return fmt.Sprintf("Invalid file descriptor '%s'", string(e))
+}
+
+// activationFiles returns the files passed by systemd socket activation,
+// indexed by name. The environment is examined only once, and the
+// variables are unset so they are not inherited by child processes.
+func activationFiles() map[string][]*os.File {
+	activation.once.Do(func() {
+		activation.files = make(map[string][]*os.File)
+		pid, err := strconv.Atoi(os.Getenv("LISTEN_PID"))
+		if err != nil || pid != os.Getpid() {
+			return
+		}
+		n, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+		if err != nil || n <= 0 {
+			return
+		}
+		names := strings.Split(os.Getenv("LISTEN_FDNAMES"), ":")
+		for i := 0; i < n; i++ {
+			name := "unknown"
+			if i < len(names) && names[i] != "" {
+				name = names[i]
+			}
+			fd := listenFDsStart + i
+			f := os.NewFile(uintptr(fd), name)
+			activation.files[name] = append(activation.files[name], f)
+		}
+		os.Unsetenv("LISTEN_PID")
+		os.Unsetenv("LISTEN_FDS")
+		os.Unsetenv("LISTEN_FDNAMES")
+	})
+	return activation.files
+}
+
+func isInheritedNetwork(n string) bool {
+	return n == systemdNetwork || n == fdNetwork
+}
+
+func validateInherited(network, address string) error {
+	if network == fdNetwork {
+		if fd, err := strconv.Atoi(address); err != nil || fd < 0 {
+			return errInvalidFD(address)
+		}
+	} else if address == "" {
+		return errNoActivationSocket(address)
+	}
+	return nil
+}
+
+// inheritedFiles returns the files referred to by an address in the
+// "systemd" or "fd" network.
+func inheritedFiles(network, address string) ([]*os.File, error) {
+	if network == fdNetwork {
+		fd, err := strconv.Atoi(address)
+		if err != nil || fd < 0 {
+			return nil, errInvalidFD(address)
+		}
+		inheritedFDs.Lock()
+		defer inheritedFDs.Unlock()
+		f, ok := inheritedFDs.files[fd]
+		if !ok {
+			if inheritedFDs.files == nil {
+				inheritedFDs.files = make(map[int]*os.File)
+			}
+			f = os.NewFile(uintptr(fd), "fd:"+address)
+			inheritedFDs.files[fd] = f
+		}
+		return []*os.File{f}, nil
+	}
+	files := activationFiles()[address]
+	if len(files) == 0 {
+		return nil, errNoActivationSocket(address)
+	}
+	return files, nil
+}
+
+// inheritedListener returns a listener on the first stream socket referred
+// to by the address.
+func inheritedListener(network, address string) (net.Listener, error) {
+	files, err := inheritedFiles(network, address)
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range files {
+		var l net.Listener
+		if l, err = net.FileListener(f); err == nil {
+			return l, nil
+		}
+	}
+	return nil, err
+}
+
+// inheritedPacketConn returns a connection on the first datagram socket
+// referred to by the address.
+func inheritedPacketConn(network, address string) (net.PacketConn, error) {
+	files, err := inheritedFiles(network, address)
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range files {
+		var c net.PacketConn
+		if c, err = net.FilePacketConn(f); err == nil {
+			return c, nil
+		}
+	}
+	return nil, err
+}
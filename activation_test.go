@@ -0,0 +1,92 @@
+/*
+ * Copyright 2018 Farsight Security, Inc.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package config
+
+import (
+	"net"
+	"os"
+	"strconv"
+	"testing"
+)
+
+// listenerFile returns a listener's socket as a file, as it would be
+// inherited from a parent process.
+func listenerFile(t *testing.T, network string) *os.File {
+	t.Helper()
+	switch network {
+	case "tcp":
+		l, err := net.ListenTCP("tcp", &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer l.Close()
+		f, err := l.File()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return f
+	default:
+		c, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer c.Close()
+		f, err := c.File()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return f
+	}
+}
+
+func TestInheritedAddr(t *testing.T) {
+	var a Addr
+	checkOK(t, a.Set("fd:3"), a.Network() == "fd" && a.String() == "3")
+	checkOK(t, a.Set("systemd:http"), a.Network() == "systemd")
+	checkErr(t, a.Set("fd:x"))
+	checkErr(t, a.Set("fd:-1"))
+	checkErr(t, a.Set("systemd:"))
+}
+
+func TestInheritedFD(t *testing.T) {
+	f := listenerFile(t, "tcp")
+	defer f.Close()
+	var a Addr
+	checkOK(t, a.Set("fd:"+strconv.Itoa(int(f.Fd()))), true)
+	l, err := a.Listen()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	c, err := net.Dial("tcp", l.Addr().String())
+	checkOK(t, err, c != nil)
+	c.Close()
+}
+
+func TestInheritedSystemd(t *testing.T) {
+	activationFiles()
+	tf, uf := listenerFile(t, "tcp"), listenerFile(t, "udp")
+	activation.files["dns"] = []*os.File{uf, tf}
+	defer delete(activation.files, "dns")
+
+	var a Addr
+	checkOK(t, a.Set("systemd:dns"), true)
+	l, err := a.Listen()
+	checkOK(t, err, l != nil && l.Addr().Network() == "tcp")
+	l.Close()
+	c, err := a.ListenPacket()
+	checkOK(t, err, c != nil && c.LocalAddr().Network() == "udp")
+	c.Close()
+
+	checkOK(t, a.Set("systemd:missing"), true)
+	_, err = a.Listen()
+	checkErr(t, err)
+	_, err = a.ListenPacket()
+	checkErr(t, err)
+}
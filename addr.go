@@ -12,13 +12,45 @@ import (
 	"encoding/json"
 	"fmt"
 	"net"
+	"strconv"
 	"strings"
+	"sync"
 )
 
 // Addr is a generic network address with JSON and YAML Marshaler and
 // Unmarshaler methods.
+//
+// An Addr is written as "net:addr", e.g., "tcp:localhost:53" or
+// "unix:/run/app.sock", or in URL style as "net://addr", e.g.,
+// "tcp://localhost:53" or "unix:///run/app.sock". For the "tcp", "udp" and
+// "ip" networks, the host and port are checked for valid syntax.
+//
+// In addition to the networks supported by the net package, an Addr may
+// refer to a socket inherited from the parent process: "systemd:name" for
+// a socket passed by systemd socket activation with the given name, or
+// "fd:N" for file descriptor N. Listen and ListenPacket return listeners on
+// these sockets, so the same configuration field works with and without
+// socket activation.
+//
+// An unset Addr marshals as the empty string.
 type Addr struct{ net.Addr }
 
+// DefaultAddr is an Addr which may omit its network, port, or both, e.g.,
+// "localhost:53" or "[::1]" rather than "udp:localhost:53" or
+// "udp:[::1]:53". DefaultNetwork and DefaultPort are set before Set or
+// unmarshaling, e.g.:
+//
+//      cfg := Config{
+//              Listen: config.DefaultAddr{DefaultNetwork: "udp", DefaultPort: "53"},
+//      }
+//
+// DefaultPort applies to TCP and UDP addresses.
+type DefaultAddr struct {
+	Addr
+	DefaultNetwork string
+	DefaultPort    string
+}
+
 type addr struct {
 	Net  string
 	Addr string
@@ -39,16 +71,210 @@ func (e errAddrFormatInvalid) Error() string {
 		" should be net:addr", string(e))
 }
 
-// Set satisfies flag.Value for use with command line flags.
+type errAddrMissingPort string
+
+func (e errAddrMissingPort) Error() string {
+	return fmt.Sprintf("Missing port in address '%s'", string(e))
+}
+
+type errAddrInvalidPort struct{ addr, port string }
+
+func (e errAddrInvalidPort) Error() string {
+	return fmt.Sprintf("Invalid port '%s' in address '%s'", e.port, e.addr)
+}
+
+type errAddrInvalidHost struct{ addr, host string }
+
+func (e errAddrInvalidHost) Error() string {
+	return fmt.Sprintf("Invalid host '%s' in address '%s'", e.host, e.addr)
+}
+
+var addrNetworks = map[string]bool{
+	"tcp": true, "tcp4": true, "tcp6": true,
+	"udp": true, "udp4": true, "udp6": true,
+	"ip": true, "ip4": true, "ip6": true,
+	"unix": true, "unixgram": true, "unixpacket": true,
+	systemdNetwork: true, fdNetwork: true,
+}
+
+// Set satisfies flag.Value for use with command line flags. The empty
+// string leaves the Addr unset.
 func (a *Addr) Set(s string) error {
-	l := strings.SplitN(s, ":", 2)
-	if len(l) < 2 {
-		return errAddrFormatInvalid(s)
+	if s == "" {
+		a.Addr = nil
+		return nil
 	}
-	a.Addr = &addr{l[0], l[1]}
+	na, err := parseAddr(s, "", "")
+	if err != nil {
+		return err
+	}
+	a.Addr = na
 	return nil
 }
 
+// Set satisfies flag.Value for use with command line flags, applying the
+// DefaultAddr's default network and port. The empty string leaves the
+// DefaultAddr unset.
+func (a *DefaultAddr) Set(s string) error {
+	if s == "" {
+		a.Addr.Addr = nil
+		return nil
+	}
+	na, err := parseAddr(s, a.DefaultNetwork, a.DefaultPort)
+	if err != nil {
+		return err
+	}
+	a.Addr.Addr = na
+	return nil
+}
+
+// UnmarshalJSON satisfies the json.Unmarshaler interface
+func (a *DefaultAddr) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	return a.Set(s)
+}
+
+// UnmarshalYAML satisfies the yaml.Unmarshaler interface
+func (a *DefaultAddr) UnmarshalYAML(u func(interface{}) error) error {
+	var s string
+	if err := u(&s); err != nil {
+		return err
+	}
+	return a.Set(s)
+}
+
+// parseAddr splits s into its network and address, applying the default
+// network and port if needed, and validates the address for the network.
+func parseAddr(s, defNet, defPort string) (*addr, error) {
+	var n, rest string
+	if i := strings.Index(s, "://"); i >= 0 && addrNetworks[s[:i]] {
+		n, rest = s[:i], s[i+3:]
+		if !isUnixNetwork(n) {
+			rest = strings.TrimSuffix(rest, "/")
+		}
+	} else if i := strings.Index(s, ":"); i >= 0 && addrNetworks[s[:i]] {
+		n, rest = s[:i], s[i+1:]
+	} else if defNet != "" {
+		n, rest = defNet, s
+	} else {
+		return nil, errAddrFormatInvalid(s)
+	}
+
+	switch {
+	case isInheritedNetwork(n):
+		if err := validateInherited(n, rest); err != nil {
+			return nil, err
+		}
+	case isUnixNetwork(n):
+		if rest == "" {
+			return nil, errAddrFormatInvalid(s)
+		}
+	case strings.HasPrefix(n, "ip"):
+		if rest != "" && !validHost(rest) {
+			return nil, errAddrInvalidHost{s, rest}
+		}
+	default:
+		var err error
+		if rest, err = validHostPort(s, rest, defPort); err != nil {
+			return nil, err
+		}
+	}
+	return &addr{n, rest}, nil
+}
+
+// validHostPort checks the syntax of the host:port address hp, adding
+// defPort if hp has no port.
+func validHostPort(s, hp, defPort string) (string, error) {
+	host, port, err := net.SplitHostPort(hp)
+	if err != nil {
+		if defPort == "" {
+			return "", errAddrMissingPort(s)
+		}
+		host, port = hp, defPort
+		if strings.HasPrefix(host, "[") && strings.HasSuffix(host, "]") {
+			host = host[1 : len(host)-1]
+		}
+		if strings.ContainsAny(host, "[]") || (strings.Contains(host, ":") && net.ParseIP(zoneless(host)) == nil) {
+			return "", errAddrInvalidHost{s, host}
+		}
+		hp = net.JoinHostPort(host, port)
+	}
+	if host != "" && !validHost(host) {
+		return "", errAddrInvalidHost{s, host}
+	}
+	if !validPort(port) {
+		return "", errAddrInvalidPort{s, port}
+	}
+	return hp, nil
+}
+
+func zoneless(host string) string {
+	if i := strings.LastIndex(host, "%"); i > 0 {
+		return host[:i]
+	}
+	return host
+}
+
+// validHost returns true if host is an IP address, optionally with a zone,
+// or a syntactically valid host name.
+func validHost(host string) bool {
+	if net.ParseIP(zoneless(host)) != nil {
+		return true
+	}
+	if len(host) > 253 {
+		return false
+	}
+	for _, label := range strings.Split(strings.TrimSuffix(host, "."), ".") {
+		if label == "" || len(label) > 63 ||
+			label[0] == '-' || label[len(label)-1] == '-' {
+			return false
+		}
+		for _, c := range label {
+			if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' ||
+				c >= '0' && c <= '9' || c == '-' || c == '_') {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// validPort returns true if port is a number from 0 to 65535 or a service
+// name.
+func validPort(port string) bool {
+	if port == "" {
+		return false
+	}
+	if p, err := strconv.Atoi(port); err == nil {
+		return p >= 0 && p <= 65535
+	}
+	for _, c := range port {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' ||
+			c >= '0' && c <= '9' || c == '-' || c == '_') {
+			return false
+		}
+	}
+	return true
+}
+
+// addrString returns the net:addr form of a, or the empty string if a
+// is unset.
+func addrString(a Addr) string {
+	if a.Addr == nil {
+		return ""
+	}
+	return fmt.Sprintf("%s:%s", a.Network(), a.String())
+}
+
+// IsSet returns true if the Addr was assigned a value with Set or by
+// unmarshaling.
+func (a Addr) IsSet() bool {
+	return a.Addr != nil
+}
+
 // UnmarshalJSON satisfies the json.Unmarshaler interface
 func (a *Addr) UnmarshalJSON(b []byte) error {
 	var s string
@@ -69,12 +295,12 @@ func (a *Addr) UnmarshalYAML(u func(interface{}) error) error {
 
 // MarshalJSON satisfies the json.Marshaler interface
 func (a Addr) MarshalJSON() ([]byte, error) {
-	return json.Marshal(fmt.Sprintf("%s:%s", a.Network(), a.String()))
+	return json.Marshal(addrString(a))
 }
 
 // MarshalYAML satisfies the yaml.Marshaler interface
 func (a Addr) MarshalYAML() (interface{}, error) {
-	return fmt.Sprintf("%s:%s", a.Network(), a.String()), nil
+	return addrString(a), nil
 }
 
 type errInvalidUDPNetwork string
@@ -95,24 +321,75 @@ func (e errInvalidUnixNetwork) Error() string {
 	return fmt.Sprintf("Invalid Unix network '%s'", string(e))
 }
 
+// addrSources records the string each TCPAddr or UDPAddr was resolved
+// from by Set, keyed by the resolved address, so that it marshals as it
+// was given rather than as the address it resolved to. An entry is only
+// used while the address is unchanged since it was resolved.
+var addrSources sync.Map
+
+type addrSource struct{ source, resolved string }
+
+func setAddrSource(a net.Addr, s string) {
+	addrSources.Store(a, addrSource{s, a.String()})
+}
+
+// sourceString returns the string a was resolved from by Set, or the
+// network and address of a if it was not set by Set or has changed.
+func sourceString(a net.Addr) string {
+	if v, ok := addrSources.Load(a); ok {
+		if src := v.(addrSource); src.resolved == a.String() {
+			return src.source
+		}
+	}
+	return addrString(Addr{Addr: a})
+}
+
 // UDPAddr is an address restricted to be in the network "udp",
-// "udp4", or "udp6". Other networks are considered an error.
+// "udp4", or "udp6". Other networks are considered an error. The network
+// may be omitted, defaulting to "udp". A host name is resolved to a single
+// address when the UDPAddr is set; see UDPHost to resolve it when used.
+//
+// A UDPAddr marshals as the string it was set from. A UDPAddr assigned
+// directly marshals as its network and address. The empty string leaves
+// the UDPAddr unset, and an unset UDPAddr marshals as the empty string.
 type UDPAddr struct{ *net.UDPAddr }
 
 // Set satisfies flag.Value for use in command line arguments
 func (u *UDPAddr) Set(s string) (err error) {
-	var a Addr
-	if err = a.Set(s); err != nil {
+	if s == "" {
+		u.UDPAddr = nil
+		return nil
+	}
+	a, err := parseAddr(s, "udp", "")
+	if err != nil {
 		return
 	}
-	n := a.Network()
-	if n != "udp" && n != "udp4" && n != "udp6" {
-		return errInvalidUDPNetwork(n)
+	if !isUDPNetwork(a.Net) {
+		return errInvalidUDPNetwork(a.Net)
+	}
+	if u.UDPAddr, err = net.ResolveUDPAddr(a.Net, a.Addr); err == nil {
+		setAddrSource(u.UDPAddr, s)
 	}
-	u.UDPAddr, err = net.ResolveUDPAddr(n, a.String())
 	return
 }
 
+func isUDPNetwork(n string) bool {
+	return n == "udp" || n == "udp4" || n == "udp6"
+}
+
+// IsSet returns true if the UDPAddr was assigned a value with Set or by
+// unmarshaling.
+func (u UDPAddr) IsSet() bool {
+	return u.UDPAddr != nil
+}
+
+func (u UDPAddr) source() string {
+	if u.UDPAddr == nil {
+		return ""
+	}
+	return sourceString(u.UDPAddr)
+}
+
 // UnmarshalJSON satisfies the json.Unmarshaler interface
 func (u *UDPAddr) UnmarshalJSON(b []byte) error {
 	var s string
@@ -133,34 +410,60 @@ func (u *UDPAddr) UnmarshalYAML(unmarshal func(interface{}) error) error {
 
 // MarshalJSON satisfies the json.Marshaler interface
 func (u UDPAddr) MarshalJSON() ([]byte, error) {
-	a := Addr{u.UDPAddr}
-	return a.MarshalJSON()
+	return json.Marshal(u.source())
 }
 
 // MarshalYAML satisfies the yaml.Marshaler interface
 func (u UDPAddr) MarshalYAML() (interface{}, error) {
-	a := Addr{u.UDPAddr}
-	return a.MarshalYAML()
+	return u.source(), nil
 }
 
 // TCPAddr is an address restricted to be in the "tcp", "tcp4", or "tcp6"
-// networks.
+// networks. The network may be omitted, defaulting to "tcp". A host name
+// is resolved to a single address when the TCPAddr is set; see TCPHost to
+// resolve it when used.
+//
+// A TCPAddr marshals as the string it was set from. A TCPAddr assigned
+// directly marshals as its network and address. The empty string leaves
+// the TCPAddr unset, and an unset TCPAddr marshals as the empty string.
 type TCPAddr struct{ *net.TCPAddr }
 
 // Set satisfies flag.Value for use in command line arguments
 func (t *TCPAddr) Set(s string) (err error) {
-	var a Addr
-	if err = a.Set(s); err != nil {
+	if s == "" {
+		t.TCPAddr = nil
+		return nil
+	}
+	a, err := parseAddr(s, "tcp", "")
+	if err != nil {
 		return
 	}
-	n := a.Network()
-	if n != "tcp" && n != "tcp4" && n != "tcp6" {
-		return errInvalidTCPNetwork(n)
+	if !isTCPNetwork(a.Net) {
+		return errInvalidTCPNetwork(a.Net)
+	}
+	if t.TCPAddr, err = net.ResolveTCPAddr(a.Net, a.Addr); err == nil {
+		setAddrSource(t.TCPAddr, s)
 	}
-	t.TCPAddr, err = net.ResolveTCPAddr(n, a.String())
 	return
 }
 
+func isTCPNetwork(n string) bool {
+	return n == "tcp" || n == "tcp4" || n == "tcp6"
+}
+
+// IsSet returns true if the TCPAddr was assigned a value with Set or by
+// unmarshaling.
+func (t TCPAddr) IsSet() bool {
+	return t.TCPAddr != nil
+}
+
+func (t TCPAddr) source() string {
+	if t.TCPAddr == nil {
+		return ""
+	}
+	return sourceString(t.TCPAddr)
+}
+
 // UnmarshalJSON satisfies the json.Unmarshaler interface
 func (t *TCPAddr) UnmarshalJSON(b []byte) error {
 	var s string
@@ -181,34 +484,49 @@ func (t *TCPAddr) UnmarshalYAML(u func(interface{}) error) error {
 
 // MarshalJSON satisfies the json.Marshaler interface
 func (t TCPAddr) MarshalJSON() ([]byte, error) {
-	a := Addr{t.TCPAddr}
-	return a.MarshalJSON()
+	return json.Marshal(t.source())
 }
 
 // MarshalYAML satisfies the yaml.Marshaler interface
 func (t TCPAddr) MarshalYAML() (interface{}, error) {
-	a := Addr{t.TCPAddr}
-	return a.MarshalYAML()
+	return t.source(), nil
 }
 
 // UnixAddr is a unix-domain socket address in the "unix", "unixpacket",
-// or "unixgram" network
+// or "unixgram" network. The network may be omitted, defaulting to "unix".
+// The empty string leaves the UnixAddr unset.
 type UnixAddr struct{ *net.UnixAddr }
 
 // Set satisfies flag.Value for use in command line arguments
 func (u *UnixAddr) Set(s string) (err error) {
-	var a Addr
-	if err = a.Set(s); err != nil {
+	if s == "" {
+		u.UnixAddr = nil
+		return nil
+	}
+	a, err := parseAddr(s, "unix", "")
+	if err != nil {
 		return
 	}
-	n := a.Network()
-	if n != "unix" && n != "unixpacket" && n != "unixgram" {
-		return errInvalidUnixNetwork(n)
+	if !isUnixNetwork(a.Net) {
+		return errInvalidUnixNetwork(a.Net)
 	}
-	u.UnixAddr, err = net.ResolveUnixAddr(n, a.String())
+	u.UnixAddr, err = net.ResolveUnixAddr(a.Net, a.Addr)
 	return
 }
 
+// IsSet returns true if the UnixAddr was assigned a value with Set or by
+// unmarshaling.
+func (u UnixAddr) IsSet() bool {
+	return u.UnixAddr != nil
+}
+
+func (u UnixAddr) addr() Addr {
+	if u.UnixAddr == nil {
+		return Addr{}
+	}
+	return Addr{Addr: u.UnixAddr}
+}
+
 // UnmarshalJSON satisfies the json.Unmarshaler interface
 func (u *UnixAddr) UnmarshalJSON(b []byte) error {
 	var s string
@@ -229,12 +547,110 @@ func (u *UnixAddr) UnmarshalYAML(unmarshal func(interface{}) error) error {
 
 // MarshalJSON satisfies the json.Marshaler interface
 func (u UnixAddr) MarshalJSON() ([]byte, error) {
-	a := Addr{u.UnixAddr}
-	return a.MarshalJSON()
+	return u.addr().MarshalJSON()
 }
 
 // MarshalYAML satisfies the yaml.Marshaler interface
 func (u UnixAddr) MarshalYAML() (interface{}, error) {
-	a := Addr{u.UnixAddr}
-	return a.MarshalYAML()
+	return u.addr().MarshalYAML()
+}
+
+// UnixSocket is a UnixAddr on which to listen, with the file Mode, Owner,
+// and Group, if set, to apply to the socket file created by Listen or
+// ListenPacket. In JSON and YAML, they may be given along with the address
+// as a dictionary:
+//
+//      admin:
+//        address: unix:/run/app/admin.sock
+//        mode: rw-rw----
+//        group: app
+//
+// The mode may be octal or symbolic, as for FileMode, and the owner and
+// group may be names or numeric IDs, as for User and Group.
+//
+// Otherwise, the UnixSocket is represented by its address string alone.
+type UnixSocket struct {
+	UnixAddr
+	Mode  FileMode
+	Owner User
+	Group Group
+}
+
+type unixSocketConfig struct {
+	Address string `json:"address" yaml:"address"`
+	Mode    string `json:"mode,omitempty" yaml:"mode,omitempty"`
+	Owner   string `json:"owner,omitempty" yaml:"owner,omitempty"`
+	Group   string `json:"group,omitempty" yaml:"group,omitempty"`
+}
+
+func (u *UnixSocket) setConfig(c unixSocketConfig) error {
+	if err := u.UnixAddr.Set(c.Address); err != nil {
+		return err
+	}
+	u.Mode, u.Owner, u.Group = FileMode{}, User{}, Group{}
+	if c.Mode != "" {
+		if err := u.Mode.Set(c.Mode); err != nil {
+			return err
+		}
+	}
+	if c.Owner != "" {
+		if err := u.Owner.Set(c.Owner); err != nil {
+			return err
+		}
+	}
+	if c.Group != "" {
+		return u.Group.Set(c.Group)
+	}
+	return nil
+}
+
+func (u UnixSocket) config() unixSocketConfig {
+	c := unixSocketConfig{Owner: u.Owner.String(), Group: u.Group.String()}
+	c.Address = addrString(u.addr())
+	if u.Mode.IsSet() {
+		c.Mode = u.Mode.String()
+	}
+	return c
+}
+
+func (u UnixSocket) hasOptions() bool {
+	return u.Mode.IsSet() || u.Owner.IsSet() || u.Group.IsSet()
+}
+
+// UnmarshalJSON satisfies the json.Unmarshaler interface
+func (u *UnixSocket) UnmarshalJSON(b []byte) error {
+	var c unixSocketConfig
+	if err := json.Unmarshal(b, &c.Address); err != nil {
+		if err := json.Unmarshal(b, &c); err != nil {
+			return err
+		}
+	}
+	return u.setConfig(c)
+}
+
+// UnmarshalYAML satisfies the yaml.Unmarshaler interface
+func (u *UnixSocket) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var c unixSocketConfig
+	if err := unmarshal(&c.Address); err != nil {
+		if err := unmarshal(&c); err != nil {
+			return err
+		}
+	}
+	return u.setConfig(c)
+}
+
+// MarshalJSON satisfies the json.Marshaler interface
+func (u UnixSocket) MarshalJSON() ([]byte, error) {
+	if u.hasOptions() {
+		return json.Marshal(u.config())
+	}
+	return u.UnixAddr.MarshalJSON()
+}
+
+// MarshalYAML satisfies the yaml.Marshaler interface
+func (u UnixSocket) MarshalYAML() (interface{}, error) {
+	if u.hasOptions() {
+		return u.config(), nil
+	}
+	return u.UnixAddr.MarshalYAML()
 }
@@ -0,0 +1,63 @@
+/*
+ * Copyright 2018 Farsight Security, Inc.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package config
+
+import (
+	"encoding/json"
+	"net"
+	"testing"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+func TestAddrSet(t *testing.T) {
+	var a Addr
+	checkOK(t, nil, !a.IsSet())
+	checkOK(t, a.Set("tcp:localhost:53"), a.IsSet() && a.Network() == "tcp" && a.String() == "localhost:53")
+	checkOK(t, a.Set("udp://[::1]:53/"), a.Network() == "udp" && a.String() == "[::1]:53")
+	checkOK(t, a.Set("unix:///run/app.sock"), a.Network() == "unix" && a.String() == "/run/app.sock")
+	checkOK(t, a.Set("ip:192.0.2.1"), a.Network() == "ip")
+	checkOK(t, a.Set("tcp::http"), a.String() == ":http")
+	checkOK(t, a.Set(""), !a.IsSet())
+
+	for _, s := range []string{"localhost:53", "tcp:localhost", "tcp:localhost:99999",
+		"tcp:bad_-host-:53", "tcp:-x:53", "unix:", "ip:not an ip", "foo:bar"} {
+		checkErr(t, a.Set(s))
+	}
+}
+
+func TestAddrLiteral(t *testing.T) {
+	a := Addr{&net.TCPAddr{IP: net.IPv4(192, 0, 2, 1), Port: 80}}
+	checkOK(t, nil, a.IsSet())
+	checkJSON(t, a, `"tcp:192.0.2.1:80"`)
+	checkJSON(t, Addr{}, `""`)
+	checkYAML(t, Addr{}, `""`)
+}
+
+func TestDefaultAddr(t *testing.T) {
+	a := DefaultAddr{DefaultNetwork: "udp", DefaultPort: "53"}
+	checkOK(t, a.Set("localhost"), a.Network() == "udp" && a.String() == "localhost:53")
+	checkOK(t, a.Set("[::1]"), a.String() == "[::1]:53")
+	checkOK(t, a.Set("tcp:192.0.2.1"), a.Network() == "tcp" && a.String() == "192.0.2.1:53")
+	checkOK(t, a.Set("192.0.2.1:5353"), a.String() == "192.0.2.1:5353")
+	checkOK(t, a.Set(""), !a.IsSet())
+	checkErr(t, a.Set("[::1"))
+	checkErr(t, a.Set("localhost:99999"))
+
+	var c struct {
+		Listen DefaultAddr `json:"listen" yaml:"listen"`
+	}
+	c.Listen.DefaultNetwork = "tcp"
+	checkOK(t, json.Unmarshal([]byte(`{"listen":":8080"}`), &c), c.Listen.String() == ":8080")
+	checkOK(t, yaml.Unmarshal([]byte(`listen: unix:/run/x.sock`), &c), c.Listen.Network() == "unix")
+	checkErr(t, yaml.Unmarshal([]byte(`listen: localhost`), &c))
+
+	b, err := json.Marshal(c)
+	checkOK(t, err, string(b) == `{"listen":"unix:/run/x.sock"}`)
+}
@@ -0,0 +1,327 @@
+/*
+ * Copyright 2018 Farsight Security, Inc.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Backoff is an exponential backoff policy for retries, as it appears in a
+// JSON or YAML config, e.g.:
+//
+//      retry:
+//        initial: 100ms
+//        max: 30s
+//        multiplier: 2
+//        jitter: 0.2
+//        maxAttempts: 5
+//
+// As a flag or a string, a Backoff is written as comma-separated key=value
+// pairs using the same keys, e.g.,
+// "initial=100ms,max=30s,multiplier=2,jitter=0.2,maxAttempts=5".
+//
+// The delay before each retry is Initial (by default, 100ms) multiplied by
+// Multiplier (by default, 2) for each earlier retry, limited to Max if set. If Jitter is
+// set, the delay is chosen at random within that fraction of this value,
+// e.g., a Jitter of 0.2 gives delays from 80% to 120% of it. If
+// MaxAttempts is set, it limits the total number of attempts, including
+// the first.
+type Backoff struct {
+	Initial     Duration `json:"initial,omitempty" yaml:"initial,omitempty"`
+	Max         Duration `json:"max,omitempty" yaml:"max,omitempty"`
+	Multiplier  float64  `json:"multiplier,omitempty" yaml:"multiplier,omitempty"`
+	Jitter      float64  `json:"jitter,omitempty" yaml:"jitter,omitempty"`
+	MaxAttempts int      `json:"maxAttempts,omitempty" yaml:"maxAttempts,omitempty"`
+	set         bool
+}
+
+type backoff Backoff
+
+type errBackoffInvalid struct{ value, reason string }
+
+func (e errBackoffInvalid) Error() string {
+	return fmt.Sprintf("Invalid backoff '%s': %s", e.value, e.reason)
+}
+
+func (b Backoff) validate(s string) error {
+	switch {
+	case b.Initial.Duration < 0 || b.Max.Duration < 0:
+		return errBackoffInvalid{s, "negative delay"}
+	case b.Multiplier != 0 && b.Multiplier < 1:
+		return errBackoffInvalid{s, "multiplier must be at least 1"}
+	case b.Jitter < 0 || b.Jitter > 1:
+		return errBackoffInvalid{s, "jitter must be between 0 and 1"}
+	case b.MaxAttempts < 0:
+		return errBackoffInvalid{s, "negative maxAttempts"}
+	}
+	return nil
+}
+
+// Set parses a Backoff from comma-separated key=value pairs, satisfying
+// flag.Value. Keys not given are left unchanged, and the empty string
+// leaves the Backoff unchanged.
+func (b *Backoff) Set(s string) error {
+	if s == "" {
+		return nil
+	}
+	nb := *b
+	for _, kv := range splitList(s) {
+		i := strings.Index(kv, "=")
+		if i < 0 {
+			return errBackoffInvalid{s, fmt.Sprintf("expected key=value, found '%s'", kv)}
+		}
+		k, v := strings.TrimSpace(kv[:i]), strings.TrimSpace(kv[i+1:])
+		var err error
+		switch k {
+		case "initial":
+			err = nb.Initial.Set(v)
+		case "max":
+			err = nb.Max.Set(v)
+		case "multiplier":
+			nb.Multiplier, err = strconv.ParseFloat(v, 64)
+		case "jitter":
+			nb.Jitter, err = strconv.ParseFloat(v, 64)
+		case "maxAttempts":
+			nb.MaxAttempts, err = strconv.Atoi(v)
+		default:
+			return errBackoffInvalid{s, fmt.Sprintf("unknown key '%s'", k)}
+		}
+		if err != nil {
+			return errBackoffInvalid{s, fmt.Sprintf("invalid %s '%s'", k, v)}
+		}
+	}
+	if err := nb.validate(s); err != nil {
+		return err
+	}
+	nb.set = true
+	*b = nb
+	return nil
+}
+
+// IsSet returns true if the Backoff was assigned a value with Set or by
+// unmarshaling, or has any field set.
+func (b Backoff) IsSet() bool {
+	return b != Backoff{}
+}
+
+// String returns the Backoff as comma-separated key=value pairs, omitting
+// those not set.
+func (b Backoff) String() string {
+	var kv []string
+	if b.Initial.Duration != 0 {
+		kv = append(kv, "initial="+b.Initial.String())
+	}
+	if b.Max.Duration != 0 {
+		kv = append(kv, "max="+b.Max.String())
+	}
+	if b.Multiplier != 0 {
+		kv = append(kv, "multiplier="+strconv.FormatFloat(b.Multiplier, 'g', -1, 64))
+	}
+	if b.Jitter != 0 {
+		kv = append(kv, "jitter="+strconv.FormatFloat(b.Jitter, 'g', -1, 64))
+	}
+	if b.MaxAttempts != 0 {
+		kv = append(kv, "maxAttempts="+strconv.Itoa(b.MaxAttempts))
+	}
+	return strings.Join(kv, ",")
+}
+
+const defaultBackoffInitial = 100 * time.Millisecond
+
+// Delay returns the delay before retry n, counting from 1 for the retry
+// following the first attempt, without jitter.
+func (b Backoff) Delay(n int) time.Duration {
+	if n < 1 {
+		n = 1
+	}
+	initial := b.Initial.Duration
+	if initial == 0 {
+		initial = defaultBackoffInitial
+	}
+	mult := b.Multiplier
+	if mult == 0 {
+		mult = 2
+	}
+	d := float64(initial) * math.Pow(mult, float64(n-1))
+	if b.Max.Duration > 0 && d > float64(b.Max.Duration) {
+		return b.Max.Duration
+	}
+	if d >= math.MaxInt64 {
+		return math.MaxInt64
+	}
+	return time.Duration(d)
+}
+
+// Next returns the delay, with jitter applied, to wait after attempt n
+// (counting from 1) fails before the next attempt. It returns false if
+// MaxAttempts is set and attempt n was the last.
+func (b Backoff) Next(n int) (time.Duration, bool) {
+	if b.MaxAttempts > 0 && n >= b.MaxAttempts {
+		return 0, false
+	}
+	d := b.Delay(n)
+	if b.Jitter > 0 && d > 0 {
+		f := 1 + b.Jitter*(2*rand.Float64()-1)
+		d = time.Duration(float64(d) * f)
+		if b.Max.Duration > 0 && d > b.Max.Duration {
+			d = b.Max.Duration
+		}
+	}
+	return d, true
+}
+
+// UnmarshalJSON satisfies the json.Unmarshaler interface
+func (b *Backoff) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		return b.Set(s)
+	}
+	var nb Backoff
+	if err := json.Unmarshal(data, (*backoff)(&nb)); err != nil {
+		return err
+	}
+	if err := nb.validate(string(data)); err != nil {
+		return err
+	}
+	nb.set = true
+	*b = nb
+	return nil
+}
+
+// UnmarshalYAML satisfies the yaml.Unmarshaler interface
+func (b *Backoff) UnmarshalYAML(u func(interface{}) error) error {
+	var s string
+	if err := u(&s); err == nil {
+		return b.Set(s)
+	}
+	var nb Backoff
+	if err := u((*backoff)(&nb)); err != nil {
+		return err
+	}
+	if err := nb.validate(nb.String()); err != nil {
+		return err
+	}
+	nb.set = true
+	*b = nb
+	return nil
+}
+
+// DurationRange is a range of durations, written as two Durations
+// separated by "-", e.g., "1s-5s", or as a single Duration for a range
+// containing only that duration. Durations in the range may not be negative.
+type DurationRange struct {
+	Min, Max Duration
+	set      bool
+}
+
+type errDurationRangeInvalid string
+
+func (e errDurationRangeInvalid) Error() string {
+	return fmt.Sprintf("Invalid duration range '%s': should be min-max",
+		string(e))
+}
+
+// Set satisfies the flag.Value interface. The empty string leaves the
+// DurationRange unset.
+func (r *DurationRange) Set(s string) error {
+	var nr DurationRange
+	if s == "" {
+		*r = nr
+		return nil
+	}
+	lo, hi := s, s
+	if i := strings.Index(s, "-"); i > 0 {
+		lo, hi = s[:i], s[i+1:]
+	}
+	if nr.Min.Set(lo) != nil || nr.Max.Set(hi) != nil ||
+		nr.Min.Duration < 0 || nr.Min.Duration > nr.Max.Duration {
+		return errDurationRangeInvalid(s)
+	}
+	nr.set = true
+	*r = nr
+	return nil
+}
+
+// String satisfies the flag.Value interface. An unset DurationRange is
+// the empty string.
+func (r DurationRange) String() string {
+	if !r.IsSet() {
+		return ""
+	}
+	if r.Min.Duration == r.Max.Duration {
+		return r.Min.String()
+	}
+	return r.Min.String() + "-" + r.Max.String()
+}
+
+// IsSet returns true if the DurationRange was assigned a value with Set
+// or by unmarshaling, or has a nonzero Min or Max.
+func (r DurationRange) IsSet() bool {
+	return r != DurationRange{}
+}
+
+// Contains returns true if d is within the DurationRange.
+func (r DurationRange) Contains(d time.Duration) bool {
+	return d >= r.Min.Duration && d <= r.Max.Duration
+}
+
+// Clamp returns d limited to the DurationRange.
+func (r DurationRange) Clamp(d time.Duration) time.Duration {
+	switch {
+	case d < r.Min.Duration:
+		return r.Min.Duration
+	case d > r.Max.Duration:
+		return r.Max.Duration
+	}
+	return d
+}
+
+// Random returns a duration chosen uniformly at random from the
+// DurationRange, e.g., for a jittered polling interval.
+func (r DurationRange) Random() time.Duration {
+	span := r.Max.Duration - r.Min.Duration
+	if span <= 0 {
+		return r.Min.Duration
+	}
+	return r.Min.Duration + time.Duration(rand.Int63n(int64(span)+1))
+}
+
+// MarshalJSON satisfies the json.Marshaler interface
+func (r DurationRange) MarshalJSON() ([]byte, error) {
+	return json.Marshal(r.String())
+}
+
+// MarshalYAML satisfies the yaml.Marshaler interface
+func (r DurationRange) MarshalYAML() (interface{}, error) {
+	return r.String(), nil
+}
+
+// UnmarshalJSON satisfies the json.Unmarshaler interface
+func (r *DurationRange) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	return r.Set(s)
+}
+
+// UnmarshalYAML satisfies the yaml.Unmarshaler interface
+func (r *DurationRange) UnmarshalYAML(u func(interface{}) error) error {
+	var s string
+	if err := u(&s); err != nil {
+		return err
+	}
+	return r.Set(s)
+}
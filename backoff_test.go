@@ -0,0 +1,95 @@
+/*
+ * Copyright 2018 Farsight Security, Inc.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package config
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+func TestBackoff(t *testing.T) {
+	var b Backoff
+	checkOK(t, nil, !b.IsSet())
+	checkOK(t, b.Set("initial=100ms,max=1s,maxAttempts=5"), b.IsSet() &&
+		b.Initial.Duration == 100*time.Millisecond && b.MaxAttempts == 5)
+	checkOK(t, nil, b.String() == "initial=100ms,max=1s,maxAttempts=5")
+
+	for n, want := range []time.Duration{100, 100, 200, 400, 800, 1000, 1000} {
+		if d := b.Delay(n); d != want*time.Millisecond {
+			t.Errorf("Delay(%d) = %v, want %v", n, d, want*time.Millisecond)
+		}
+	}
+	_, ok := b.Next(4)
+	checkOK(t, nil, ok)
+	_, ok = b.Next(5)
+	checkOK(t, nil, !ok)
+
+	checkOK(t, b.Set("jitter=0.5"), b.Jitter == 0.5 && b.MaxAttempts == 5)
+	for i := 0; i < 100; i++ {
+		d, _ := b.Next(2)
+		if d < 100*time.Millisecond || d > 300*time.Millisecond {
+			t.Fatalf("Next(2) = %v, out of range", d)
+		}
+	}
+
+	for _, s := range []string{"initial", "foo=1", "initial=x", "max=-1s",
+		"multiplier=0.5", "jitter=2", "maxAttempts=-1"} {
+		checkErr(t, b.Set(s))
+	}
+
+	checkJSON(t, Backoff{Initial: Duration{time.Second}, Multiplier: 1.5},
+		`{"initial":"1s","max":"0s","multiplier":1.5}`)
+	checkYAML(t, Backoff{Max: Duration{time.Minute}}, `max: 1m0s`)
+}
+
+func TestBackoffUnmarshal(t *testing.T) {
+	var c struct {
+		Retry Backoff `json:"retry" yaml:"retry"`
+	}
+	checkOK(t, json.Unmarshal([]byte(`{"retry":"initial=1s"}`), &c), c.Retry.Initial.Duration == time.Second)
+	checkOK(t, yaml.Unmarshal([]byte("retry:\n  initial: 2s\n  jitter: 0.1\n"), &c),
+		c.Retry.Initial.Duration == 2*time.Second && c.Retry.Jitter == 0.1)
+	checkErr(t, yaml.Unmarshal([]byte("retry:\n  jitter: 3\n"), &c))
+
+	c.Retry = Backoff{}
+	checkOK(t, json.Unmarshal([]byte(`{"retry":{}}`), &c), c.Retry.IsSet() &&
+		c.Retry.Delay(1) == 100*time.Millisecond && c.Retry.Delay(3) == 400*time.Millisecond)
+	checkErr(t, json.Unmarshal([]byte(`{"retry":{"multiplier":0.1}}`), &c))
+
+	// An empty policy is set, with the defaults.
+	c.Retry = Backoff{}
+	checkOK(t, json.Unmarshal([]byte(`{"retry":{}}`), &c), c.Retry.IsSet())
+}
+
+func TestDurationRange(t *testing.T) {
+	var r DurationRange
+	checkOK(t, nil, !r.IsSet() && r.String() == "")
+	checkOK(t, r.Set("1s-5s"), r.IsSet() && r.Min.Duration == time.Second && r.Max.Duration == 5*time.Second)
+	checkOK(t, nil, r.Contains(3*time.Second) && !r.Contains(6*time.Second) &&
+		r.Clamp(0) == time.Second && r.Clamp(time.Minute) == 5*time.Second)
+	for i := 0; i < 100; i++ {
+		if d := r.Random(); !r.Contains(d) {
+			t.Fatalf("Random() = %v, out of range", d)
+		}
+	}
+
+	checkOK(t, r.Set("0s-5s"), r.IsSet())
+	checkOK(t, r.Set("0s"), r.IsSet() && r.String() == "0s")
+	checkOK(t, r.Set(""), !r.IsSet())
+	for _, s := range []string{"5s-1s", "1s-", "x-5s", "-5s"} {
+		checkErr(t, r.Set(s))
+	}
+
+	checkJSON(t, DurationRange{Min: Duration{time.Second}, Max: Duration{time.Minute}}, `"1s-1m0s"`)
+	checkJSON(t, DurationRange{}, `""`)
+	checkYAML(t, DurationRange{Max: Duration{time.Second}}, `0s-1s`)
+}
@@ -0,0 +1,157 @@
+/*
+ * Copyright 2018 Farsight Security, Inc.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// ByteSize provides JSON, YAML and text Marshaling and Unmarshaling for
+// quantities of bytes. The string format is a decimal number, optionally
+// fractional, followed by an optional unit, e.g., "512", "64KiB", "10MB",
+// "1.5GiB". Units are case insensitive, with SI units (kB, MB, GB, TB, PB,
+// EB) being powers of 1000 and IEC units (KiB, MiB, GiB, TiB, PiB, EiB)
+// powers of 1024. A bare number or the unit "B" denotes bytes.
+type ByteSize struct {
+	Bytes uint64
+	set   bool
+}
+
+var byteSizeUnits = []struct {
+	name string
+	size uint64
+}{
+	{"EiB", 1 << 60}, {"EB", 1e18},
+	{"PiB", 1 << 50}, {"PB", 1e15},
+	{"TiB", 1 << 40}, {"TB", 1e12},
+	{"GiB", 1 << 30}, {"GB", 1e9},
+	{"MiB", 1 << 20}, {"MB", 1e6},
+	{"KiB", 1 << 10}, {"kB", 1e3},
+	{"B", 1},
+}
+
+type errByteSizeInvalid string
+
+func (e errByteSizeInvalid) Error() string {
+	return fmt.Sprintf("Invalid byte size '%s'", string(e))
+}
+
+// ParseByteSize parses a byte size string in the format accepted by
+// ByteSize.
+func ParseByteSize(s string) (uint64, error) {
+	s = strings.TrimSpace(s)
+	i := strings.IndexFunc(s, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.'
+	})
+	if i < 0 {
+		i = len(s)
+	}
+	num, unit := s[:i], strings.TrimSpace(s[i:])
+	mult := uint64(1)
+	if unit != "" {
+		mult = 0
+		for _, u := range byteSizeUnits {
+			if strings.EqualFold(unit, u.name) {
+				mult = u.size
+				break
+			}
+		}
+		if mult == 0 {
+			return 0, errByteSizeInvalid(s)
+		}
+	}
+	if n, err := strconv.ParseUint(num, 10, 64); err == nil {
+		if n > math.MaxUint64/mult {
+			return 0, errByteSizeInvalid(s)
+		}
+		return n * mult, nil
+	}
+	f, err := strconv.ParseFloat(num, 64)
+	if err != nil || f*float64(mult) >= math.MaxUint64 {
+		return 0, errByteSizeInvalid(s)
+	}
+	return uint64(f * float64(mult)), nil
+}
+
+// String returns the byte size in the largest unit which represents it
+// exactly, preferring IEC units over SI units, e.g., "64KiB", "10MB",
+// "1kB" for 1000 bytes, or "1001B".
+func (b ByteSize) String() string {
+	if b.Bytes == 0 {
+		return "0B"
+	}
+	for _, u := range byteSizeUnits {
+		if b.Bytes%u.size == 0 {
+			return strconv.FormatUint(b.Bytes/u.size, 10) + u.name
+		}
+	}
+	return strconv.FormatUint(b.Bytes, 10) + "B"
+}
+
+// Set satisfies the flag.Value interface for use as a command line
+// flag.
+func (b *ByteSize) Set(s string) (err error) {
+	if b.Bytes, err = ParseByteSize(s); err == nil {
+		b.set = true
+	}
+	return
+}
+
+// IsSet returns true if the ByteSize was assigned a value with Set or by
+// unmarshaling.
+func (b ByteSize) IsSet() bool {
+	return b.set
+}
+
+// MarshalText satisfies encoding.TextMarshaler
+func (b ByteSize) MarshalText() ([]byte, error) {
+	return []byte(b.String()), nil
+}
+
+// UnmarshalText satisfies encoding.TextUnmarshaler
+func (b *ByteSize) UnmarshalText(text []byte) error {
+	return b.Set(string(text))
+}
+
+// MarshalJSON satisfies json.Marshaler
+func (b ByteSize) MarshalJSON() ([]byte, error) {
+	return json.Marshal(b.String())
+}
+
+// MarshalYAML satisfies yaml.Marshaler
+func (b ByteSize) MarshalYAML() (interface{}, error) {
+	return b.String(), nil
+}
+
+// UnmarshalJSON satisfies json.Unmarshaler. A JSON number is taken as a
+// count of bytes.
+func (b *ByteSize) UnmarshalJSON(data []byte) error {
+	var n json.Number
+	if err := json.Unmarshal(data, &n); err == nil {
+		return b.Set(n.String())
+	}
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	return b.Set(s)
+}
+
+// UnmarshalYAML satisfies yaml.Unmarshaler
+func (b *ByteSize) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var s string
+	if err := unmarshal(&s); err != nil {
+		return err
+	}
+	return b.Set(s)
+}
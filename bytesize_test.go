@@ -0,0 +1,59 @@
+/*
+ * Copyright 2018 Farsight Security, Inc.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package config
+
+import (
+	"encoding/json"
+	"testing"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+func TestByteSizeSet(t *testing.T) {
+	var b ByteSize
+	checkOK(t, nil, !b.IsSet())
+	checkOK(t, b.Set("512"), b.Bytes == 512 && b.IsSet())
+	checkOK(t, b.Set("64KiB"), b.Bytes == 65536)
+	checkOK(t, b.Set("10 mb"), b.Bytes == 10e6)
+	checkOK(t, b.Set("1.5GiB"), b.Bytes == 3<<29)
+	checkOK(t, b.Set("6EiB"), b.Bytes == 6<<60)
+
+	for _, s := range []string{"", "x", "1XB", "-1", "16EiB", "1e3", "18446744073709551616"} {
+		checkErr(t, b.Set(s))
+	}
+}
+
+func TestByteSizeString(t *testing.T) {
+	for n, want := range map[uint64]string{
+		0:       "0B",
+		1:       "1B",
+		1000:    "1kB",
+		1001:    "1001B",
+		1024:    "1KiB",
+		65536:   "64KiB",
+		10e6:    "10MB",
+		3 << 29: "1536MiB",
+		1 << 60: "1EiB",
+	} {
+		if got := (ByteSize{Bytes: n}).String(); got != want {
+			t.Errorf("ByteSize(%d): got %s, want %s", n, got, want)
+		}
+	}
+}
+
+func TestByteSizeMarshal(t *testing.T) {
+	checkJSON(t, ByteSize{Bytes: 1000}, `"1kB"`)
+	checkYAML(t, ByteSize{Bytes: 65536}, `64KiB`)
+
+	var b ByteSize
+	checkOK(t, json.Unmarshal([]byte(`4096`), &b), b.Bytes == 4096)
+	checkOK(t, yaml.Unmarshal([]byte(`2048`), &b), b.Bytes == 2048)
+	checkErr(t, json.Unmarshal([]byte(`true`), &b))
+	checkErr(t, yaml.Unmarshal([]byte(`2 XB`), &b))
+}
@@ -0,0 +1,363 @@
+/*
+ * Copyright 2018 Farsight Security, Inc.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package config
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Dialer contains the configuration for making outbound connections as it
+// appears in a JSON or YAML config, e.g.:
+//
+//      dialer:
+//        timeout: 10s
+//        keepAlive: 30s
+//        localAddr: tcp:192.0.2.1:0
+//        proxy: socks5://proxy.example.com:1080
+//        tls:
+//          rootCAFiles: [/etc/app/ca.pem]
+//
+// All fields are optional. The Proxy URL may have the scheme "http" for a
+// proxy supporting the CONNECT method, or "socks5" or "socks5h" for a SOCKS
+// version 5 proxy, with optional user name and password.
+type Dialer struct {
+	Timeout   Duration `json:"timeout,omitempty" yaml:"timeout,omitempty"`
+	KeepAlive Duration `json:"keepAlive,omitempty" yaml:"keepAlive,omitempty"`
+	LocalAddr *Addr    `json:"localAddr,omitempty" yaml:"localAddr,omitempty"`
+	TLS       *TLS     `json:"tls,omitempty" yaml:"tls,omitempty"`
+	Proxy     *URL     `json:"proxy,omitempty" yaml:"proxy,omitempty"`
+}
+
+type errUnsupportedProxy string
+
+func (e errUnsupportedProxy) Error() string {
+	return fmt.Sprintf("Unsupported proxy scheme '%s'", string(e))
+}
+
+// NetDialer returns a net.Dialer configured with the Dialer's timeout and
+// keepalive settings. The local address is not set, as it depends on the
+// network dialed.
+func (d Dialer) NetDialer() *net.Dialer {
+	return &net.Dialer{
+		Timeout:   d.Timeout.Duration,
+		KeepAlive: d.KeepAlive.Duration,
+	}
+}
+
+type errLocalAddrNetwork struct{ local, network string }
+
+func (e errLocalAddrNetwork) Error() string {
+	return fmt.Sprintf("Local address network '%s' does not match network '%s'",
+		e.local, e.network)
+}
+
+// localAddr resolves the Dialer's LocalAddr for dialing on network, so that,
+// e.g., a "tcp6" dial fails with a clear error for an IPv4 LocalAddr, and a
+// "tcp" dial is restricted to remote addresses of the LocalAddr's family.
+func (d Dialer) localAddr(network string) (net.Addr, error) {
+	if d.LocalAddr == nil || d.LocalAddr.Addr == nil {
+		return nil, nil
+	}
+	a := d.LocalAddr
+	if networkKind(a.Network()) != networkKind(network) {
+		return nil, errLocalAddrNetwork{a.Network(), network}
+	}
+	switch network {
+	case "tcp", "tcp4", "tcp6":
+		return net.ResolveTCPAddr(network, a.String())
+	case "udp", "udp4", "udp6":
+		return net.ResolveUDPAddr(network, a.String())
+	case "unix", "unixgram", "unixpacket":
+		return net.ResolveUnixAddr(network, a.String())
+	case "ip", "ip4", "ip6":
+		return net.ResolveIPAddr(network, a.String())
+	}
+	return nil, net.UnknownNetworkError(network)
+}
+
+// networkKind returns the network n without any address family suffix,
+// e.g., "tcp" for "tcp6", and "unix" for any unix network.
+func networkKind(n string) string {
+	if isUnixNetwork(n) {
+		return "unix"
+	}
+	if i := strings.IndexByte(n, ':'); i >= 0 {
+		n = n[:i]
+	}
+	return strings.TrimRight(n, "46")
+}
+
+// dialDirect dials address, through the proxy if one is configured, without
+// TLS.
+func (d Dialer) dialDirect(ctx context.Context, network, address string) (net.Conn, error) {
+	nd := d.NetDialer()
+	var err error
+	if d.Proxy == nil || d.Proxy.URL == nil {
+		if nd.LocalAddr, err = d.localAddr(network); err != nil {
+			return nil, err
+		}
+		return nd.DialContext(ctx, network, address)
+	}
+	if nd.LocalAddr, err = d.localAddr("tcp"); err != nil {
+		return nil, err
+	}
+
+	p := d.Proxy.URL
+	if d.Timeout.Duration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, d.Timeout.Duration)
+		defer cancel()
+	}
+	conn, err := nd.DialContext(ctx, "tcp", proxyHostPort(p))
+	if err != nil {
+		return nil, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+		defer conn.SetDeadline(time.Time{})
+	}
+	switch p.Scheme {
+	case "http":
+		conn, err = httpConnect(conn, p, address)
+	case "socks5", "socks5h":
+		err = socks5Connect(conn, p, address)
+	default:
+		err = errUnsupportedProxy(p.Scheme)
+	}
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+// DialContext connects to address on the named network, through the proxy
+// if one is configured, and performs a TLS handshake if the Dialer has TLS
+// configured. If the TLS configuration does not specify a ServerName, the
+// host part of address is used.
+//
+// DialContext may be used wherever a DialContext function is needed, e.g.,
+// as cfg.Dialer.DialContext.
+func (d Dialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	conn, err := d.dialDirect(ctx, network, address)
+	if err != nil || d.TLS == nil || d.TLS.Config == nil {
+		return conn, err
+	}
+	tc := d.TLS.Config.Clone()
+	if tc.ServerName == "" {
+		if host, _, err := net.SplitHostPort(address); err == nil {
+			tc.ServerName = host
+		}
+	}
+	tconn := tls.Client(conn, tc)
+	if err = tconn.HandshakeContext(ctx); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return tconn, nil
+}
+
+// Dial connects to address on the named network, as with DialContext.
+func (d Dialer) Dial(network, address string) (net.Conn, error) {
+	return d.DialContext(context.Background(), network, address)
+}
+
+// defaultTLSHandshakeTimeout is the TLS handshake timeout of a Transport
+// when the Dialer has no Timeout, as for http.DefaultTransport.
+const defaultTLSHandshakeTimeout = 10 * time.Second
+
+// Transport returns an http.Transport which makes connections as
+// configured by the Dialer. The Transport handles TLS and proxying itself,
+// using the Dialer's TLS configuration and proxy URL. Its TLS handshake
+// timeout is the Dialer's Timeout, or 10 seconds if that is not set.
+func (d Dialer) Transport() *http.Transport {
+	direct := d
+	direct.Proxy = nil
+	t := &http.Transport{
+		DialContext:         direct.dialDirect,
+		TLSHandshakeTimeout: defaultTLSHandshakeTimeout,
+		ForceAttemptHTTP2:   true,
+	}
+	if d.Timeout.Duration > 0 {
+		t.TLSHandshakeTimeout = d.Timeout.Duration
+	}
+	if d.TLS != nil && d.TLS.Config != nil {
+		t.TLSClientConfig = d.TLS.Config.Clone()
+	}
+	if d.Proxy != nil && d.Proxy.URL != nil {
+		t.Proxy = http.ProxyURL(d.Proxy.URL)
+	}
+	return t
+}
+
+func proxyHostPort(p *url.URL) string {
+	if p.Port() != "" {
+		return p.Host
+	}
+	switch p.Scheme {
+	case "http":
+		return net.JoinHostPort(p.Hostname(), "80")
+	case "https":
+		return net.JoinHostPort(p.Hostname(), "443")
+	}
+	return net.JoinHostPort(p.Hostname(), "1080")
+}
+
+// bufferedConn is a net.Conn whose reads start with data already read from
+// the connection into a bufio.Reader.
+type bufferedConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+func (c *bufferedConn) Read(b []byte) (int, error) {
+	if c.r.Buffered() > 0 {
+		return c.r.Read(b)
+	}
+	return c.Conn.Read(b)
+}
+
+// httpConnect requests a tunnel to address through the HTTP proxy at p on
+// conn. It returns a connection which also yields any data the proxy sent
+// after its response.
+func httpConnect(conn net.Conn, p *url.URL, address string) (net.Conn, error) {
+	req := &http.Request{
+		Method: "CONNECT",
+		URL:    &url.URL{Opaque: address},
+		Host:   address,
+		Header: make(http.Header),
+	}
+	if p.User != nil {
+		pass, _ := p.User.Password()
+		auth := p.User.Username() + ":" + pass
+		req.Header.Set("Proxy-Authorization",
+			"Basic "+base64.StdEncoding.EncodeToString([]byte(auth)))
+	}
+	if err := req.Write(conn); err != nil {
+		return conn, err
+	}
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, req)
+	if err != nil {
+		return conn, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return conn, fmt.Errorf("Proxy CONNECT to %s failed: %s", address,
+			resp.Status)
+	}
+	if br.Buffered() > 0 {
+		return &bufferedConn{conn, br}, nil
+	}
+	return conn, nil
+}
+
+var errSOCKS5Failed = errors.New("SOCKS5 proxy handshake failed")
+
+func socks5Connect(conn net.Conn, p *url.URL, address string) error {
+	host, portStr, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	port, err := strconv.ParseUint(portStr, 10, 16)
+	if err != nil {
+		return err
+	}
+
+	method := byte(0x00)
+	if p.User != nil {
+		method = 0x02
+	}
+	if _, err = conn.Write([]byte{0x05, 0x01, method}); err != nil {
+		return err
+	}
+	buf := make([]byte, 2)
+	if _, err = io.ReadFull(conn, buf); err != nil {
+		return err
+	}
+	if buf[0] != 0x05 || buf[1] != method {
+		return errSOCKS5Failed
+	}
+
+	if method == 0x02 {
+		user := p.User.Username()
+		pass, _ := p.User.Password()
+		if len(user) > 255 || len(pass) > 255 {
+			return errSOCKS5Failed
+		}
+		req := []byte{0x01, byte(len(user))}
+		req = append(req, user...)
+		req = append(req, byte(len(pass)))
+		req = append(req, pass...)
+		if _, err = conn.Write(req); err != nil {
+			return err
+		}
+		if _, err = io.ReadFull(conn, buf); err != nil {
+			return err
+		}
+		if buf[1] != 0x00 {
+			return errSOCKS5Failed
+		}
+	}
+
+	req := []byte{0x05, 0x01, 0x00}
+	if ip := net.ParseIP(host); ip == nil {
+		if len(host) > 255 {
+			return errSOCKS5Failed
+		}
+		req = append(req, 0x03, byte(len(host)))
+		req = append(req, host...)
+	} else if ip4 := ip.To4(); ip4 != nil {
+		req = append(req, 0x01)
+		req = append(req, ip4...)
+	} else {
+		req = append(req, 0x04)
+		req = append(req, ip.To16()...)
+	}
+	req = append(req, byte(port>>8), byte(port))
+	if _, err = conn.Write(req); err != nil {
+		return err
+	}
+
+	resp := make([]byte, 4)
+	if _, err = io.ReadFull(conn, resp); err != nil {
+		return err
+	}
+	if resp[0] != 0x05 || resp[1] != 0x00 {
+		return errSOCKS5Failed
+	}
+	var skip int
+	switch resp[3] {
+	case 0x01:
+		skip = 4
+	case 0x04:
+		skip = 16
+	case 0x03:
+		if _, err = io.ReadFull(conn, buf[:1]); err != nil {
+			return err
+		}
+		skip = int(buf[0])
+	default:
+		return errSOCKS5Failed
+	}
+	_, err = io.ReadFull(conn, make([]byte, skip+2))
+	return err
+}
@@ -0,0 +1,121 @@
+/*
+ * Copyright 2018 Farsight Security, Inc.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package config
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// proxyServer accepts one CONNECT request on l, checks its
+// Proxy-Authorization header, and replies with status followed
+// immediately by extra.
+func proxyServer(t *testing.T, l net.Listener, status, extra string) {
+	c, err := l.Accept()
+	if err != nil {
+		return
+	}
+	defer c.Close()
+	req, err := http.ReadRequest(bufio.NewReader(c))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if req.Method != "CONNECT" || req.Host != "example.com:443" {
+		t.Errorf("bad request: %s %s", req.Method, req.Host)
+	}
+	if req.Header.Get("Proxy-Authorization") != "Basic dXNlcjpwYXNz" {
+		t.Errorf("missing proxy credentials")
+	}
+	c.Write([]byte("HTTP/1.1 " + status + "\r\n\r\n" + extra))
+	io.Copy(io.Discard, c)
+}
+
+func TestDialerHTTPProxy(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go proxyServer(t, l, "200 Connection established", "hello")
+
+	d := Dialer{Timeout: Duration{5 * time.Second}}
+	checkOK(t, yaml.Unmarshal([]byte("proxy: http://user:pass@"+l.Addr().String()), &d), d.Proxy != nil)
+	conn, err := d.Dial("tcp", "example.com:443")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	b := make([]byte, 5)
+	_, err = io.ReadFull(conn, b)
+	checkOK(t, err, string(b) == "hello")
+}
+
+func TestDialerHTTPProxyRefused(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go proxyServer(t, l, "403 Forbidden", "")
+
+	u, _ := url.Parse("http://user:pass@" + l.Addr().String())
+	d := Dialer{Proxy: &URL{URL: u}}
+	_, err = d.DialContext(context.Background(), "tcp", "example.com:443")
+	checkErr(t, err)
+}
+
+func TestDialerLocalAddr(t *testing.T) {
+	var d Dialer
+	a, err := d.localAddr("tcp")
+	checkOK(t, err, a == nil)
+
+	d.LocalAddr = &Addr{}
+	checkOK(t, d.LocalAddr.Set("tcp:127.0.0.1:0"), true)
+	a, err = d.localAddr("tcp4")
+	checkOK(t, err, a != nil && a.Network() == "tcp" && a.String() == "127.0.0.1:0")
+	_, err = d.localAddr("tcp6")
+	checkErr(t, err)
+	_, err = d.localAddr("udp")
+	checkErr(t, err)
+
+	l, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	conn, err := d.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.Close()
+	_, err = d.Dial("udp", l.Addr().String())
+	checkErr(t, err)
+}
+
+func TestDialerTransport(t *testing.T) {
+	var d Dialer
+	checkOK(t, nil, d.Transport().TLSHandshakeTimeout == 10*time.Second)
+	d.Timeout.Set("3s")
+	checkOK(t, nil, d.Transport().TLSHandshakeTimeout == 3*time.Second)
+
+	d.Proxy = &URL{}
+	checkOK(t, d.Proxy.Set("http://proxy.example.com:3128"), d.Transport().Proxy != nil)
+	checkOK(t, nil, proxyHostPort(d.Proxy.URL) == "proxy.example.com:3128")
+	d.Proxy.Set("socks5://proxy.example.com")
+	checkOK(t, nil, proxyHostPort(d.Proxy.URL) == "proxy.example.com:1080")
+}
@@ -9,22 +9,287 @@
 package config
 
 import (
+	"bytes"
 	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
 	"time"
 )
 
 // Duration provides JSON Marshaling and Unmarshaling for time.Duration
 // values. The JSON string format is that supported by the Parse() and
-// String() methods of time.Duration, e.g., "1m30s", "100ms", etc.
+// String() methods of time.Duration, e.g., "1m30s", "100ms", etc.,
+// extended with the units "d" (24 hours) and "w" (7 days), e.g., "7d" or
+// "1w2d12h". ISO 8601 durations such as "P1DT2H" or "PT30S" are also
+// accepted. A bare number other than "0" is an error.
+//
+// IsSet reports whether the Duration is nonzero. An explicit "0s" cannot
+// be told apart from an absent setting, so a Duration cannot be tagged
+// `config:"required"`; use Optional[Duration] instead.
+//
+// The HumanDuration type marshals in a more readable form, the
+// UnitDuration type accepts bare numbers as a count of a unit set per
+// field, and the Seconds, Millis and Nanos types accept and marshal
+// numbers of their units.
 type Duration struct{ time.Duration }
 
+const (
+	day  = 24 * time.Hour
+	week = 7 * day
+)
+
+type errDurationInvalid string
+
+func (e errDurationInvalid) Error() string {
+	return fmt.Sprintf("Invalid duration '%s'", string(e))
+}
+
+type errDurationISO struct{ value, reason string }
+
+func (e errDurationISO) Error() string {
+	return fmt.Sprintf("Invalid ISO 8601 duration '%s': %s", e.value, e.reason)
+}
+
+// ParseDuration parses a duration string in the format accepted by
+// Duration, without a unit for bare numbers.
+func ParseDuration(s string) (time.Duration, error) {
+	return parseDuration(s, 0)
+}
+
+// ParseDurationUnit parses a duration string as ParseDuration does, taking
+// a bare number as a count of unit, e.g., "30" as 30 seconds if unit is
+// time.Second.
+func ParseDurationUnit(s string, unit time.Duration) (time.Duration, error) {
+	return parseDuration(s, unit)
+}
+
+func parseDuration(s string, unit time.Duration) (time.Duration, error) {
+	orig := s
+	s = strings.TrimSpace(s)
+	neg := false
+	if s != "" && (s[0] == '-' || s[0] == '+') {
+		neg, s = s[0] == '-', s[1:]
+	}
+	var d time.Duration
+	var err error
+	switch {
+	case s == "":
+		return 0, errDurationInvalid(orig)
+	case s[0] == 'P' || s[0] == 'p':
+		d, err = parseISODuration(orig, s[1:])
+	case strings.Trim(s, "0123456789.") == "":
+		d, err = parseNumber(orig, s, unit)
+	default:
+		d, err = parseUnits(orig, s)
+	}
+	if neg {
+		d = -d
+	}
+	return d, err
+}
+
+// parseUnits parses a sequence of numbers with units, as accepted by
+// time.ParseDuration with the addition of days and weeks.
+func parseUnits(orig, s string) (time.Duration, error) {
+	var total time.Duration
+	for s != "" {
+		i := strings.IndexFunc(s, func(r rune) bool {
+			return (r < '0' || r > '9') && r != '.'
+		})
+		if i <= 0 {
+			return 0, errDurationInvalid(orig)
+		}
+		j := strings.IndexAny(s[i:], "0123456789.")
+		if j < 0 {
+			j = len(s) - i
+		}
+		num, unit := s[:i], s[i:i+j]
+		s = s[i+j:]
+
+		var d time.Duration
+		var err error
+		switch unit {
+		case "d", "w":
+			f, perr := strconv.ParseFloat(num, 64)
+			if perr != nil {
+				return 0, errDurationInvalid(orig)
+			}
+			mult := day
+			if unit == "w" {
+				mult = week
+			}
+			d, err = scaleDuration(orig, f, mult)
+		default:
+			d, err = time.ParseDuration(num + unit)
+		}
+		if err != nil {
+			return 0, errDurationInvalid(orig)
+		}
+		if total > math.MaxInt64-d {
+			return 0, errDurationInvalid(orig)
+		}
+		total += d
+	}
+	return total, nil
+}
+
+// parseISODuration parses the part of an ISO 8601 duration following the
+// "P". Years and months, which vary in length, are not supported.
+func parseISODuration(orig, s string) (time.Duration, error) {
+	if s == "" {
+		return 0, errDurationISO{orig, "no components"}
+	}
+	var total time.Duration
+	inTime := false
+	for s != "" {
+		if s[0] == 'T' || s[0] == 't' {
+			if inTime || len(s) == 1 {
+				return 0, errDurationISO{orig, "misplaced 'T'"}
+			}
+			inTime, s = true, s[1:]
+			continue
+		}
+		i := strings.IndexFunc(s, func(r rune) bool {
+			return (r < '0' || r > '9') && r != '.' && r != ','
+		})
+		if i <= 0 {
+			return 0, errDurationISO{orig, "expected number"}
+		}
+		f, err := strconv.ParseFloat(strings.Replace(s[:i], ",", ".", 1), 64)
+		if err != nil {
+			return 0, errDurationISO{orig, "invalid number"}
+		}
+		var unit time.Duration
+		switch c := s[i] | 0x20; {
+		case c == 'y', c == 'm' && !inTime:
+			return 0, errDurationISO{orig, "years and months are not supported"}
+		case c == 'w' && !inTime:
+			unit = week
+		case c == 'd' && !inTime:
+			unit = day
+		case c == 'h' && inTime:
+			unit = time.Hour
+		case c == 'm' && inTime:
+			unit = time.Minute
+		case c == 's' && inTime:
+			unit = time.Second
+		default:
+			return 0, errDurationISO{orig, fmt.Sprintf("unexpected '%c'", s[i])}
+		}
+		d, err := scaleDuration(orig, f, unit)
+		if err != nil || total > math.MaxInt64-d {
+			return 0, errDurationInvalid(orig)
+		}
+		total += d
+		s = s[i+1:]
+	}
+	return total, nil
+}
+
+func scaleDuration(orig string, f float64, unit time.Duration) (time.Duration, error) {
+	v := f * float64(unit)
+	if v >= math.MaxInt64 || v < math.MinInt64 {
+		return 0, errDurationInvalid(orig)
+	}
+	return time.Duration(v), nil
+}
+
+// formatDuration formats d using weeks and days as well as the units of
+// time.Duration.String, omitting zero components, e.g., "1w", "1d12h",
+// "1m30s", or "1.5s".
+func formatDuration(d time.Duration) string {
+	if d == 0 {
+		return "0s"
+	}
+	var b strings.Builder
+	if d < 0 {
+		b.WriteByte('-')
+		if d == math.MinInt64 {
+			return d.String()
+		}
+		d = -d
+	}
+	for _, u := range []struct {
+		name string
+		size time.Duration
+	}{{"w", week}, {"d", day}, {"h", time.Hour}, {"m", time.Minute}} {
+		if n := d / u.size; n > 0 {
+			fmt.Fprintf(&b, "%d%s", n, u.name)
+			d -= n * u.size
+		}
+	}
+	if d > 0 {
+		b.WriteString(d.String())
+	}
+	return b.String()
+}
+
 // Set satisfies the flag.Value interface for use as a command line
 // flag.
-func (d *Duration) Set(s string) (err error) {
-	d.Duration, err = time.ParseDuration(s)
-	return
+func (d *Duration) Set(s string) error {
+	return d.set(s, 0)
 }
 
+// set parses s as a Duration, taking a bare number as a count of unit.
+func (d *Duration) set(s string, unit time.Duration) error {
+	v, err := parseDuration(s, unit)
+	if err != nil {
+		return err
+	}
+	d.Duration = v
+	return nil
+}
+
+// setNumber sets the Duration from the text of a JSON or YAML number of
+// unit.
+func (d *Duration) setNumber(s string, unit time.Duration) error {
+	v, err := parseNumber(s, s, unit)
+	if err != nil {
+		return err
+	}
+	d.Duration = v
+	return nil
+}
+
+// parseNumber parses s as a count of unit. Integers are scaled exactly,
+// and other numbers as floating point. A nonzero number is invalid if unit
+// is zero.
+func parseNumber(orig, s string, unit time.Duration) (time.Duration, error) {
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		if n != 0 && unit == 0 {
+			return 0, errDurationInvalid(orig)
+		}
+		v := time.Duration(n) * unit
+		if unit != 0 && v/unit != time.Duration(n) {
+			return 0, errDurationInvalid(orig)
+		}
+		return v, nil
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil || (f != 0 && unit == 0) {
+		return 0, errDurationInvalid(orig)
+	}
+	return scaleDuration(orig, f, unit)
+}
+
+// number returns the Duration as a count of unit: an integer if it is a
+// whole number of unit, or a float otherwise.
+func (d Duration) number(unit time.Duration) interface{} {
+	if d.Duration%unit == 0 {
+		return int64(d.Duration / unit)
+	}
+	return float64(d.Duration) / float64(unit)
+}
+
+// IsSet returns true if the Duration is nonzero.
+func (d Duration) IsSet() bool {
+	return d.Duration != 0
+}
+
+func (Duration) zeroIsUnset() {}
+
 // MarshalJSON satisfies json.Marshaler
 func (d Duration) MarshalJSON() ([]byte, error) {
 	return json.Marshal(d.String())
@@ -37,18 +302,221 @@ func (d Duration) MarshalYAML() (interface{}, error) {
 
 // UnmarshalJSON satisfies json.Unmarshaler
 func (d *Duration) UnmarshalJSON(b []byte) error {
+	return d.unmarshalJSON(b, 0)
+}
+
+func (d *Duration) unmarshalJSON(b []byte, unit time.Duration) error {
 	var s string
 	if err := json.Unmarshal(b, &s); err != nil {
-		return err
+		var n json.Number
+		if json.Unmarshal(b, &n) != nil {
+			return err
+		}
+		return d.setNumber(n.String(), unit)
 	}
-	return d.Set(s)
+	return d.set(s, unit)
 }
 
 // UnmarshalYAML satisfies yaml.Unmarshaler
 func (d *Duration) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	return d.unmarshalYAML(unmarshal, 0)
+}
+
+func (d *Duration) unmarshalYAML(unmarshal func(interface{}) error, unit time.Duration) error {
+	var v interface{}
+	if err := unmarshal(&v); err != nil {
+		return err
+	}
+	switch n := v.(type) {
+	case int, int64, uint64, float64:
+		return d.setNumber(fmt.Sprint(n), unit)
+	}
 	var s string
 	if err := unmarshal(&s); err != nil {
 		return err
 	}
-	return d.Set(s)
+	return d.set(s, unit)
+}
+
+// UnitDuration is a Duration which also accepts a bare number as a count
+// of Unit, which is set before Set or unmarshaling, e.g.:
+//
+//      cfg := Config{
+//              Retention: config.UnitDuration{Unit: 24 * time.Hour},
+//      }
+//
+// With this, "30", or in JSON or YAML the number 30, is 30 days. If Unit
+// is zero, a bare number other than 0 is an error, as for Duration. A
+// UnitDuration marshals as a Duration does.
+type UnitDuration struct {
+	Duration
+	Unit time.Duration
+}
+
+// Set satisfies the flag.Value interface, taking a bare number as a count
+// of Unit.
+func (u *UnitDuration) Set(s string) error {
+	return u.set(s, u.Unit)
+}
+
+// UnmarshalJSON satisfies json.Unmarshaler
+func (u *UnitDuration) UnmarshalJSON(b []byte) error {
+	return u.unmarshalJSON(b, u.Unit)
+}
+
+// UnmarshalYAML satisfies yaml.Unmarshaler
+func (u *UnitDuration) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	return u.unmarshalYAML(unmarshal, u.Unit)
+}
+
+// HumanDuration is a Duration which String and marshaling give in the most
+// readable form using days and weeks, e.g., "1w" rather than "168h0m0s".
+type HumanDuration struct{ Duration }
+
+// String returns the HumanDuration in its most readable form.
+func (h HumanDuration) String() string {
+	return formatDuration(h.Duration.Duration)
+}
+
+// MarshalJSON satisfies json.Marshaler
+func (h HumanDuration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(h.String())
+}
+
+// MarshalYAML satisfies yaml.Marshaler
+func (h HumanDuration) MarshalYAML() (interface{}, error) {
+	return h.String(), nil
+}
+
+// numericDuration implements the Seconds, Millis and Nanos types, which
+// marshal as a number of their unit unless they were given as a string.
+type numericDuration struct {
+	Duration
+	text bool
+}
+
+func (d *numericDuration) setText(s string, unit time.Duration) error {
+	if err := d.set(s, unit); err != nil {
+		return err
+	}
+	d.text = true
+	return nil
+}
+
+func (d numericDuration) marshal(unit time.Duration) interface{} {
+	if d.text {
+		return d.String()
+	}
+	return d.number(unit)
+}
+
+func (d *numericDuration) unmarshalJSON(b []byte, unit time.Duration) error {
+	if err := d.Duration.unmarshalJSON(b, unit); err != nil {
+		return err
+	}
+	b = bytes.TrimSpace(b)
+	d.text = len(b) > 0 && b[0] == '"'
+	return nil
+}
+
+func (d *numericDuration) unmarshalYAML(unmarshal func(interface{}) error, unit time.Duration) error {
+	if err := d.Duration.unmarshalYAML(unmarshal, unit); err != nil {
+		return err
+	}
+	var v interface{}
+	unmarshal(&v)
+	_, d.text = v.(string)
+	return nil
+}
+
+// Seconds is a Duration which may also be given as a number of seconds,
+// e.g., 30 or "0.25", in which case a JSON or YAML value may be a number.
+// It is marshaled as a number of seconds if it was given as a number or
+// assigned directly, and as a duration string, e.g., "1m30s", if it was
+// given as a string or with Set.
+type Seconds numericDuration
+
+// Set satisfies the flag.Value interface, taking a bare number as seconds.
+func (s *Seconds) Set(v string) error {
+	return (*numericDuration)(s).setText(v, time.Second)
+}
+
+// MarshalJSON satisfies json.Marshaler
+func (s Seconds) MarshalJSON() ([]byte, error) {
+	return json.Marshal(numericDuration(s).marshal(time.Second))
+}
+
+// MarshalYAML satisfies yaml.Marshaler
+func (s Seconds) MarshalYAML() (interface{}, error) {
+	return numericDuration(s).marshal(time.Second), nil
+}
+
+// UnmarshalJSON satisfies json.Unmarshaler
+func (s *Seconds) UnmarshalJSON(b []byte) error {
+	return (*numericDuration)(s).unmarshalJSON(b, time.Second)
+}
+
+// UnmarshalYAML satisfies yaml.Unmarshaler
+func (s *Seconds) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	return (*numericDuration)(s).unmarshalYAML(unmarshal, time.Second)
+}
+
+// Millis is a Duration which may also be given as a number of
+// milliseconds, and is marshaled as described for Seconds.
+type Millis numericDuration
+
+// Set satisfies the flag.Value interface, taking a bare number as
+// milliseconds.
+func (m *Millis) Set(v string) error {
+	return (*numericDuration)(m).setText(v, time.Millisecond)
+}
+
+// MarshalJSON satisfies json.Marshaler
+func (m Millis) MarshalJSON() ([]byte, error) {
+	return json.Marshal(numericDuration(m).marshal(time.Millisecond))
+}
+
+// MarshalYAML satisfies yaml.Marshaler
+func (m Millis) MarshalYAML() (interface{}, error) {
+	return numericDuration(m).marshal(time.Millisecond), nil
+}
+
+// UnmarshalJSON satisfies json.Unmarshaler
+func (m *Millis) UnmarshalJSON(b []byte) error {
+	return (*numericDuration)(m).unmarshalJSON(b, time.Millisecond)
+}
+
+// UnmarshalYAML satisfies yaml.Unmarshaler
+func (m *Millis) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	return (*numericDuration)(m).unmarshalYAML(unmarshal, time.Millisecond)
+}
+
+// Nanos is a Duration which may also be given as an integer number of
+// nanoseconds, and is marshaled as described for Seconds.
+type Nanos numericDuration
+
+// Set satisfies the flag.Value interface, taking a bare number as
+// nanoseconds.
+func (n *Nanos) Set(v string) error {
+	return (*numericDuration)(n).setText(v, time.Nanosecond)
+}
+
+// MarshalJSON satisfies json.Marshaler
+func (n Nanos) MarshalJSON() ([]byte, error) {
+	return json.Marshal(numericDuration(n).marshal(time.Nanosecond))
+}
+
+// MarshalYAML satisfies yaml.Marshaler
+func (n Nanos) MarshalYAML() (interface{}, error) {
+	return numericDuration(n).marshal(time.Nanosecond), nil
+}
+
+// UnmarshalJSON satisfies json.Unmarshaler
+func (n *Nanos) UnmarshalJSON(b []byte) error {
+	return (*numericDuration)(n).unmarshalJSON(b, time.Nanosecond)
+}
+
+// UnmarshalYAML satisfies yaml.Unmarshaler
+func (n *Nanos) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	return (*numericDuration)(n).unmarshalYAML(unmarshal, time.Nanosecond)
 }
@@ -0,0 +1,176 @@
+/*
+ * Copyright 2018 Farsight Security, Inc.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package config
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+func TestDurationSet(t *testing.T) {
+	var d Duration
+	checkOK(t, nil, !d.IsSet())
+	checkOK(t, d.Set("1m30s"), d.Duration == 90*time.Second)
+	checkOK(t, nil, d.IsSet() && d.String() == "1m30s")
+	checkOK(t, d.Set("1w2d"), d.Duration == 216*time.Hour)
+	checkOK(t, d.Set("0"), d.Duration == 0 && !d.IsSet())
+
+	for _, s := range []string{"", "10", "1x", "abc", "P"} {
+		checkErr(t, d.Set(s))
+	}
+}
+
+func TestDurationLiteral(t *testing.T) {
+	d := Duration{5 * time.Second}
+	checkOK(t, nil, d.IsSet() && d.String() == "5s")
+}
+
+func TestDurationMarshal(t *testing.T) {
+	checkJSON(t, Duration{90 * time.Second}, `"1m30s"`)
+	checkYAML(t, Duration{90 * time.Second}, `1m30s`)
+	checkJSON(t, Duration{}, `"0s"`)
+
+	var d Duration
+	checkErr(t, json.Unmarshal([]byte(`30`), &d))
+	checkErr(t, json.Unmarshal([]byte(`true`), &d))
+	checkErr(t, yaml.Unmarshal([]byte(`30`), &d))
+	checkErr(t, yaml.Unmarshal([]byte(`[1]`), &d))
+	checkOK(t, json.Unmarshal([]byte(`"7d"`), &d), d.Duration == 168*time.Hour)
+	checkOK(t, yaml.Unmarshal([]byte(`2h`), &d), d.Duration == 2*time.Hour)
+}
+
+func TestHumanDuration(t *testing.T) {
+	var h HumanDuration
+	checkOK(t, h.Set("168h"), h.Duration.Duration == 168*time.Hour)
+	checkOK(t, nil, h.String() == "1w")
+	checkJSON(t, HumanDuration{Duration{216*time.Hour + 90*time.Second}}, `"1w2d1m30s"`)
+	checkYAML(t, HumanDuration{Duration{36 * time.Hour}}, `1d12h`)
+}
+
+func TestUnitDuration(t *testing.T) {
+	u := UnitDuration{Unit: 24 * time.Hour}
+	checkOK(t, u.Set("30"), u.Duration.Duration == 30*day)
+	checkOK(t, u.Set("1.5"), u.Duration.Duration == 36*time.Hour)
+	checkOK(t, u.Set("2h"), u.Duration.Duration == 2*time.Hour)
+
+	c := struct {
+		Retention UnitDuration `json:"retention" yaml:"retention"`
+	}{UnitDuration{Unit: time.Second}}
+	checkOK(t, json.Unmarshal([]byte(`{"retention":90}`), &c), c.Retention.Duration.Duration == 90*time.Second)
+	checkOK(t, yaml.Unmarshal([]byte(`retention: "5"`), &c), c.Retention.Duration.Duration == 5*time.Second)
+	checkJSON(t, UnitDuration{Duration: Duration{time.Minute}}, `"1m0s"`)
+
+	d, err := ParseDurationUnit("10", time.Minute)
+	checkOK(t, err, d == 10*time.Minute)
+	_, err = ParseDurationUnit("10", 0)
+	checkErr(t, err)
+}
+
+func TestParseDurationISO(t *testing.T) {
+	for _, c := range []struct {
+		s string
+		d time.Duration
+	}{
+		{"P1DT2H", 26 * time.Hour},
+		{"PT30S", 30 * time.Second},
+		{"P2W", 336 * time.Hour},
+		{"pt1h30m", 90 * time.Minute},
+		{"PT0,5S", 500 * time.Millisecond},
+		{"PT1.5M", 90 * time.Second},
+		{"-P1D", -24 * time.Hour},
+		{"P0D", 0},
+	} {
+		d, err := ParseDuration(c.s)
+		if err != nil || d != c.d {
+			t.Errorf("%s: got %v, %v, want %v", c.s, d, err, c.d)
+		}
+	}
+
+	for _, s := range []string{"P", "PT", "P1Y", "P1M", "P1H", "PT1D", "P1DT", "PTT1H",
+		"PT1", "PDT1H", "P1.2.3D", "P200000W"} {
+		_, err := ParseDuration(s)
+		checkErr(t, err)
+	}
+	_, err := ParseDuration("P1M")
+	checkOK(t, nil, err != nil && err.Error() ==
+		"Invalid ISO 8601 duration 'P1M': years and months are not supported")
+}
+
+func TestFormatDuration(t *testing.T) {
+	for _, c := range []struct {
+		d time.Duration
+		s string
+	}{
+		{0, "0s"},
+		{168 * time.Hour, "1w"},
+		{36 * time.Hour, "1d12h"},
+		{90 * time.Second, "1m30s"},
+		{1500 * time.Millisecond, "1.5s"},
+		{-25 * time.Hour, "-1d1h"},
+		{time.Hour + time.Millisecond, "1h1ms"},
+	} {
+		if s := formatDuration(c.d); s != c.s {
+			t.Errorf("%v: got %s, want %s", c.d, s, c.s)
+		}
+		if d, err := ParseDuration(c.s); err != nil || d != c.d {
+			t.Errorf("%s: parsed as %v, %v", c.s, d, err)
+		}
+	}
+}
+
+func TestNumericDurations(t *testing.T) {
+	var s Seconds
+	checkOK(t, s.Set("30"), s.Duration.Duration == 30*time.Second)
+	checkOK(t, s.Set("0.25"), s.Duration.Duration == 250*time.Millisecond)
+	checkOK(t, s.Set("2m"), s.Duration.Duration == 2*time.Minute)
+	checkOK(t, s.Set("P1D"), s.Duration.Duration == 24*time.Hour)
+	checkErr(t, s.Set("1e400"))
+	checkErr(t, s.Set("x"))
+	checkJSON(t, Seconds{Duration: Duration{90 * time.Second}}, `90`)
+	checkJSON(t, Seconds{Duration: Duration{1500 * time.Millisecond}}, `1.5`)
+	checkYAML(t, Seconds{Duration: Duration{time.Minute}}, `60`)
+	checkOK(t, json.Unmarshal([]byte(`"1m"`), &s), s.Duration.Duration == time.Minute)
+	checkOK(t, json.Unmarshal([]byte(`0.5`), &s), s.Duration.Duration == 500*time.Millisecond)
+	checkOK(t, yaml.Unmarshal([]byte(`45`), &s), s.Duration.Duration == 45*time.Second)
+	checkErr(t, json.Unmarshal([]byte(`9.3e9`), &s))
+
+	var m Millis
+	checkOK(t, m.Set("250"), m.Duration.Duration == 250*time.Millisecond)
+	checkJSON(t, Millis{Duration: Duration{2 * time.Second}}, `2000`)
+	checkYAML(t, Millis{Duration: Duration{1500 * time.Microsecond}}, `1.5`)
+	checkOK(t, yaml.Unmarshal([]byte(`"1s"`), &m), m.Duration.Duration == time.Second)
+
+	var n Nanos
+	checkOK(t, n.Set("1000"), n.Duration.Duration == time.Microsecond)
+	checkOK(t, n.Set("9223372036854775807"), n.Duration.Duration == 1<<63-1)
+	checkErr(t, n.Set("9223372036854775808"))
+	checkJSON(t, Nanos{Duration: Duration{time.Millisecond}}, `1000000`)
+	checkOK(t, json.Unmarshal([]byte(`1234`), &n), n.Duration.Duration == 1234)
+
+	// A duration given as a string marshals as a string.
+	checkJSON(t, Seconds{Duration: Duration{time.Minute}, text: true}, `"1m0s"`)
+	checkYAML(t, Millis{Duration: Duration{time.Second}, text: true}, `1s`)
+	var c struct {
+		Timeout Seconds `json:"timeout"`
+		Poll    Millis  `json:"poll"`
+	}
+	checkOK(t, json.Unmarshal([]byte(`{"timeout":"90s","poll":250}`), &c), true)
+	b, err := json.Marshal(c)
+	checkOK(t, err, string(b) == `{"timeout":"1m30s","poll":250}`)
+	checkOK(t, yaml.Unmarshal([]byte("timeout: \"30\"\npoll: 5"), &c), c.Timeout.Duration.Duration == 30*time.Second)
+	b, err = yaml.Marshal(c)
+	checkOK(t, err, string(b) == "timeout: 30s\npoll: 5\n")
+
+	// The unit does not apply to plain Durations.
+	var d Duration
+	checkErr(t, d.Set("30"))
+}
@@ -0,0 +1,240 @@
+/*
+ * Copyright 2018 Farsight Security, Inc.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// EnumName associates a name with a value of an enumerated type.
+type EnumName[T comparable] struct {
+	Name  string
+	Value T
+}
+
+// Enum holds a value of type T given by name in a config or on the command
+// line. The names of the values of T are registered once with
+// RegisterEnum, e.g.:
+//
+//      type Mode int
+//
+//      const (
+//              ModeFast Mode = iota
+//              ModeSafe
+//      )
+//
+//      func init() {
+//              err := config.RegisterEnum(
+//                      config.EnumName[Mode]{"fast", ModeFast},
+//                      config.EnumName[Mode]{"safe", ModeSafe},
+//                      config.EnumName[Mode]{"careful", ModeSafe},
+//              )
+//              if err != nil {
+//                      panic(err)
+//              }
+//      }
+//
+//      type Config struct {
+//              Mode config.Enum[Mode] `json:"mode"`
+//      }
+//
+// Names are matched case insensitively. The first name registered for a
+// value is used by String and when marshaling; any later names for the
+// same value are accepted as aliases.
+type Enum[T comparable] struct {
+	Value T
+	set   bool
+}
+
+type enumTable[T comparable] struct {
+	values     map[string]T
+	names      map[T]string
+	valid      []string
+	registered []EnumName[T]
+}
+
+var enumTables sync.Map // reflect.Type -> *enumTable[T]
+
+type errEnumInvalid struct {
+	value string
+	valid []string
+}
+
+func (e errEnumInvalid) Error() string {
+	return fmt.Sprintf("Invalid value '%s': valid values are %s",
+		e.value, strings.Join(e.valid, ", "))
+}
+
+type errEnumValueInvalid struct{ value interface{} }
+
+func (e errEnumValueInvalid) Error() string {
+	return fmt.Sprintf("Invalid value %v: no name registered", e.value)
+}
+
+type errEnumNotRegistered struct{ typ reflect.Type }
+
+func (e errEnumNotRegistered) Error() string {
+	return fmt.Sprintf("No names registered for %s", e.typ)
+}
+
+type errEnumDuplicate struct {
+	name string
+	typ  reflect.Type
+}
+
+func (e errEnumDuplicate) Error() string {
+	return fmt.Sprintf("Duplicate name '%s' for %s", e.name, e.typ)
+}
+
+type errEnumRegistered struct{ typ reflect.Type }
+
+func (e errEnumRegistered) Error() string {
+	return fmt.Sprintf("Different names already registered for %s", e.typ)
+}
+
+func enumType[T comparable]() reflect.Type {
+	return reflect.TypeOf((*T)(nil)).Elem()
+}
+
+// RegisterEnum registers the names of the values of type T for use by
+// Enum[T]. Registering the same names for T again has no effect, so that
+// independent packages may register a shared type. It returns an error if
+// different names are already registered for T, or if a name is given more
+// than once.
+func RegisterEnum[T comparable](names ...EnumName[T]) error {
+	t := &enumTable[T]{
+		values:     make(map[string]T),
+		names:      make(map[T]string),
+		registered: append([]EnumName[T](nil), names...),
+	}
+	for _, n := range names {
+		key := strings.ToLower(n.Name)
+		if _, ok := t.values[key]; ok {
+			return errEnumDuplicate{n.Name, enumType[T]()}
+		}
+		t.values[key] = n.Value
+		if _, ok := t.names[n.Value]; !ok {
+			t.names[n.Value] = n.Name
+			t.valid = append(t.valid, n.Name)
+		}
+	}
+	if v, loaded := enumTables.LoadOrStore(enumType[T](), t); loaded {
+		if !sameEnumNames(v.(*enumTable[T]).registered, names) {
+			return errEnumRegistered{enumType[T]()}
+		}
+	}
+	return nil
+}
+
+func sameEnumNames[T comparable](a, b []EnumName[T]) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func lookupEnum[T comparable]() (*enumTable[T], error) {
+	t, ok := enumTables.Load(enumType[T]())
+	if !ok {
+		return nil, errEnumNotRegistered{enumType[T]()}
+	}
+	return t.(*enumTable[T]), nil
+}
+
+// Set satisfies the flag.Value interface.
+func (e *Enum[T]) Set(s string) error {
+	t, err := lookupEnum[T]()
+	if err != nil {
+		return err
+	}
+	v, ok := t.values[strings.ToLower(s)]
+	if !ok {
+		return errEnumInvalid{s, t.valid}
+	}
+	e.Value = v
+	e.set = true
+	return nil
+}
+
+// String returns the name of the Enum's value, or the empty string if the
+// value has no registered name.
+func (e Enum[T]) String() string {
+	s, _ := e.name()
+	return s
+}
+
+func (e Enum[T]) name() (string, error) {
+	t, err := lookupEnum[T]()
+	if err != nil {
+		return "", err
+	}
+	s, ok := t.names[e.Value]
+	if !ok {
+		return "", errEnumValueInvalid{e.Value}
+	}
+	return s, nil
+}
+
+// IsSet returns true if the Enum was assigned a value with Set or by
+// unmarshaling.
+func (e Enum[T]) IsSet() bool {
+	return e.set
+}
+
+// MarshalText satisfies the encoding.TextMarshaler interface
+func (e Enum[T]) MarshalText() ([]byte, error) {
+	s, err := e.name()
+	return []byte(s), err
+}
+
+// UnmarshalText satisfies the encoding.TextUnmarshaler interface
+func (e *Enum[T]) UnmarshalText(text []byte) error {
+	return e.Set(string(text))
+}
+
+// MarshalJSON satisfies the json.Marshaler interface
+func (e Enum[T]) MarshalJSON() ([]byte, error) {
+	s, err := e.name()
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(s)
+}
+
+// MarshalYAML satisfies the yaml.Marshaler interface
+func (e Enum[T]) MarshalYAML() (interface{}, error) {
+	return e.name()
+}
+
+// UnmarshalJSON satisfies the json.Unmarshaler interface
+func (e *Enum[T]) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	return e.Set(s)
+}
+
+// UnmarshalYAML satisfies the yaml.Unmarshaler interface
+func (e *Enum[T]) UnmarshalYAML(u func(interface{}) error) error {
+	var s string
+	if err := u(&s); err != nil {
+		return err
+	}
+	return e.Set(s)
+}
@@ -0,0 +1,72 @@
+/*
+ * Copyright 2018 Farsight Security, Inc.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package config
+
+import (
+	"testing"
+)
+
+type testMode int
+
+const (
+	testModeFast testMode = iota
+	testModeSafe
+	testModeUnnamed
+)
+
+var testModeNames = []EnumName[testMode]{
+	{"fast", testModeFast},
+	{"safe", testModeSafe},
+	{"careful", testModeSafe},
+}
+
+func init() {
+	if err := RegisterEnum(testModeNames...); err != nil {
+		panic(err)
+	}
+}
+
+func TestEnum(t *testing.T) {
+	var e Enum[testMode]
+	checkOK(t, nil, !e.IsSet())
+	checkOK(t, e.Set("SAFE"), e.Value == testModeSafe && e.IsSet() && e.String() == "safe")
+	checkOK(t, e.Set("careful"), e.Value == testModeSafe && e.String() == "safe")
+	checkJSON(t, Enum[testMode]{Value: testModeFast}, `"fast"`)
+	checkYAML(t, Enum[testMode]{Value: testModeSafe}, `safe`)
+
+	err := e.Set("slow")
+	checkOK(t, nil, err != nil && err.Error() == "Invalid value 'slow': valid values are fast, safe")
+
+	_, err = Enum[testMode]{Value: testModeUnnamed}.MarshalJSON()
+	checkErr(t, err)
+
+	type unregistered int
+	var u Enum[unregistered]
+	checkErr(t, u.Set("x"))
+}
+
+func TestRegisterEnum(t *testing.T) {
+	// Registering the same names again has no effect.
+	checkOK(t, RegisterEnum(testModeNames...), true)
+
+	checkErr(t, RegisterEnum(EnumName[testMode]{"fast", testModeFast}))
+	checkErr(t, RegisterEnum(
+		EnumName[testMode]{"fast", testModeFast},
+		EnumName[testMode]{"safe", testModeSafe},
+		EnumName[testMode]{"careful", testModeFast},
+	))
+
+	type dup int
+	checkErr(t, RegisterEnum(EnumName[dup]{"a", 1}, EnumName[dup]{"A", 2}))
+	var d Enum[dup]
+	checkErr(t, d.Set("a"))
+
+	var e Enum[testMode]
+	checkOK(t, e.Set("careful"), e.Value == testModeSafe)
+}
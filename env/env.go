@@ -33,6 +33,8 @@ import (
 	"os"
 	"strconv"
 	"time"
+
+	"github.com/farsightsec/go-config"
 )
 
 // A Value can be converted from a string.
@@ -145,13 +147,47 @@ func Float64Var(f *float64, key string) error {
 type durationValue time.Duration
 
 func (d *durationValue) Set(s string) error {
-	v, err := time.ParseDuration(s)
+	v, err := config.ParseDuration(s)
 	*d = durationValue(v)
 	return err
 }
 
 // DurationVar loads a duration value from the environment variable key into *d.
-// The value associated with key may be in any format recognized by time.ParseDuration
+// The value associated with key may be in any format recognized by
+// config.ParseDuration, e.g., "90s", "7d", or "P1DT2H".
 func DurationVar(d *time.Duration, key string) error {
 	return Var((*durationValue)(d), key)
 }
+
+type durationUnitValue struct {
+	d    *time.Duration
+	unit time.Duration
+}
+
+func (d durationUnitValue) Set(s string) error {
+	v, err := config.ParseDurationUnit(s, d.unit)
+	*d.d = v
+	return err
+}
+
+// DurationUnitVar loads a duration value from the environment variable key
+// into *d, as DurationVar does, taking a bare number as a count of unit,
+// e.g., "30" as 30 seconds if unit is time.Second.
+func DurationUnitVar(d *time.Duration, key string, unit time.Duration) error {
+	return Var(durationUnitValue{d, unit}, key)
+}
+
+type byteSizeValue uint64
+
+func (b *byteSizeValue) Set(s string) error {
+	v, err := config.ParseByteSize(s)
+	*b = byteSizeValue(v)
+	return err
+}
+
+// ByteSizeVar loads a byte size from the environment variable key into *b.
+// The value associated with key may be in any format recognized by
+// config.ParseByteSize, e.g., "512", "64KiB", or "10MB".
+func ByteSizeVar(b *uint64, key string) error {
+	return Var((*byteSizeValue)(b), key)
+}
@@ -20,6 +20,9 @@ func init() {
 	os.Setenv("TEST_BOOL_INVALID", "maybe?")
 	os.Setenv("TEST_NUM", "1048576")
 	os.Setenv("TEST_DURATION", "100ms")
+	os.Setenv("TEST_DURATION_DAYS", "1w2d")
+	os.Setenv("TEST_DURATION_ISO", "P1DT2H")
+	os.Setenv("TEST_BYTESIZE", "64KiB")
 }
 
 func checkOK(t *testing.T, err error, ok bool) {
@@ -41,6 +44,7 @@ func TestEnvTypes(t *testing.T) {
 	var s string
 	var b bool
 	var d time.Duration
+	var bs uint64
 
 	checkOK(t, IntVar(&i, "TEST_NUM"), i == 1048576)
 	checkOK(t, Int64Var(&i64, "TEST_NUM"), i64 == 1048576)
@@ -49,6 +53,14 @@ func TestEnvTypes(t *testing.T) {
 	checkOK(t, Float64Var(&f64, "TEST_NUM"), f64 == 1048576)
 	checkOK(t, StringVar(&s, "TEST_NUM"), s == "1048576")
 	checkOK(t, DurationVar(&d, "TEST_DURATION"), d == 100*time.Millisecond)
+	checkOK(t, DurationVar(&d, "TEST_DURATION_DAYS"), d == 216*time.Hour)
+	checkOK(t, DurationVar(&d, "TEST_DURATION_ISO"), d == 26*time.Hour)
+	checkOK(t, DurationUnitVar(&d, "TEST_NUM", time.Millisecond), d == 1048576*time.Millisecond)
+	checkOK(t, DurationUnitVar(&d, "TEST_DURATION", time.Second), d == 100*time.Millisecond)
+	if DurationVar(&d, "TEST_NUM") == nil {
+		t.Error("DurationVar accepted a bare number")
+	}
+	checkOK(t, ByteSizeVar(&bs, "TEST_BYTESIZE"), bs == 65536)
 	checkOK(t, BoolVar(&b, "TEST_BOOL_TRUE"), b)
 	checkOK(t, BoolVar(&b, "TEST_BOOL_FALSE"), !b)
 }
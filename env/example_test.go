@@ -54,6 +54,11 @@ func Example() {
 		log.Fatalf("Failed to load config from %s: %v", confFile, err)
 	}
 
-	// Finally, read values from command line arguments
+	// Then read values from command line arguments
 	flag.Parse()
+
+	// Finally, check that all required settings were given
+	if err := config.Validate(&conf); err != nil {
+		log.Fatal(err)
+	}
 }
@@ -10,38 +10,166 @@ package config
 
 import (
 	"encoding/json"
+	"fmt"
 	"io/ioutil"
 	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
 
 	yaml "gopkg.in/yaml.v2"
 )
 
 // LoadYAML populates the configuration from the YAML-formatted contents
-// of the file `filename`. If `required` is false, LoadYAML returns a nil
-// error if the file does not exist.
+// of the file `filename`. If `required` is false, LoadYAML does not return
+// an error if the file does not exist.
+//
+// After loading, LoadYAML checks any FilePath, DirPath and CheckedURL
+// fields, resolving relative paths against the directory of filename
+// where requested. It does not check fields tagged `config:"required"`,
+// which may yet be set by command line flags; call Validate once all
+// configuration is read.
 func LoadYAML(i interface{}, filename string, required bool) error {
 	b, err := ioutil.ReadFile(filename)
 	if err != nil {
-		if !required && err == os.ErrNotExist {
-			return nil
+		if required || !os.IsNotExist(err) {
+			return err
 		}
+	} else if err = UnmarshalYAML(b, i); err != nil {
 		return err
 	}
 
-	return yaml.Unmarshal(b, i)
+	return validate(i, filepath.Dir(filename), tagName("yaml", yamlFieldName), false)
 }
 
 // LoadJSON populates the configuration from the JSON-formatted contents
-// of the file `filename`. If `required` is false, LoadJSON returns a nil
-// error if the file does not exist.
+// of the file `filename`. If `required` is false, LoadJSON does not return
+// an error if the file does not exist.
+//
+// After loading, LoadJSON checks any FilePath, DirPath and CheckedURL
+// fields, resolving relative paths against the directory of filename
+// where requested. It does not check fields tagged `config:"required"`,
+// which may yet be set by command line flags; call Validate once all
+// configuration is read.
 func LoadJSON(i interface{}, filename string, required bool) error {
 	b, err := ioutil.ReadFile(filename)
 	if err != nil {
-		if !required && err == os.ErrNotExist {
-			return nil
+		if required || !os.IsNotExist(err) {
+			return err
 		}
+	} else if err = json.Unmarshal(b, i); err != nil {
 		return err
 	}
 
-	return json.Unmarshal(b, i)
+	return validate(i, filepath.Dir(filename), tagName("json", fieldName), false)
+}
+
+// UnmarshalYAML decodes the YAML document b into i as yaml.Unmarshal does,
+// then sets any Optional given a YAML null, such as `timeout: null` or
+// `timeout: ~`, to null. The YAML decoder zeroes such fields without
+// calling their UnmarshalYAML method, so Optionals are found by their keys
+// in the document. LoadYAML decodes with UnmarshalYAML.
+func UnmarshalYAML(b []byte, i interface{}) error {
+	if err := yaml.Unmarshal(b, i); err != nil {
+		return err
+	}
+	var doc interface{}
+	if err := yaml.Unmarshal(b, &doc); err != nil {
+		return err
+	}
+	setYAMLNulls(reflect.ValueOf(i), doc)
+	return nil
+}
+
+// A nullable value records an explicit null.
+type nullable interface {
+	setNull()
+}
+
+// setYAMLNulls walks v alongside its decoded YAML node, setting nullable
+// values whose node is null.
+func setYAMLNulls(v reflect.Value, node interface{}) {
+	if v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if !v.IsNil() {
+			setYAMLNulls(v.Elem(), node)
+		}
+		return
+	}
+	var addr interface{}
+	if v.CanAddr() && v.Addr().CanInterface() {
+		addr = v.Addr().Interface()
+	}
+	if node == nil {
+		if n, ok := addr.(nullable); ok {
+			n.setNull()
+		}
+		return
+	}
+	if c, ok := addr.(container); ok {
+		if e := c.elems(); e.IsValid() {
+			setYAMLNulls(e, node)
+		}
+		return
+	}
+	switch v.Kind() {
+	case reflect.Struct:
+		m, ok := node.(map[interface{}]interface{})
+		if !ok {
+			return
+		}
+		name := tagName("yaml", yamlFieldName)
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			tag := strings.Split(f.Tag.Get("yaml"), ",")
+			if (f.PkgPath != "" && !f.Anonymous) || tag[0] == "-" {
+				continue
+			}
+			if inlineTag(tag[1:]) {
+				setYAMLNulls(v.Field(i), node)
+				continue
+			}
+			if n, ok := m[name(f)]; ok {
+				setYAMLNulls(v.Field(i), n)
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		s, ok := node.([]interface{})
+		if !ok {
+			return
+		}
+		for i := 0; i < v.Len() && i < len(s); i++ {
+			setYAMLNulls(v.Index(i), s[i])
+		}
+	case reflect.Map:
+		m, ok := node.(map[interface{}]interface{})
+		if !ok {
+			return
+		}
+		keys := make(map[string]interface{}, len(m))
+		for k, n := range m {
+			keys[fmt.Sprint(k)] = n
+		}
+		// Map values are not addressable, so each is updated as a copy.
+		iter := v.MapRange()
+		for iter.Next() {
+			n, ok := keys[fmt.Sprint(iter.Key())]
+			if !ok {
+				continue
+			}
+			e := reflect.New(v.Type().Elem()).Elem()
+			e.Set(iter.Value())
+			setYAMLNulls(e, n)
+			v.SetMapIndex(iter.Key(), e)
+		}
+	}
+}
+
+func inlineTag(opts []string) bool {
+	for _, opt := range opts {
+		if opt == "inline" {
+			return true
+		}
+	}
+	return false
 }
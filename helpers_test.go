@@ -0,0 +1,80 @@
+/*
+ * Copyright 2018 Farsight Security, Inc.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package config
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+func checkOK(t *testing.T, err error, ok bool) {
+	t.Helper()
+	if err != nil {
+		t.Error(err)
+	}
+	if !ok {
+		t.Error("check failed")
+	}
+}
+
+func checkErr(t *testing.T, err error) {
+	t.Helper()
+	if err == nil {
+		t.Error("expected error")
+	}
+}
+
+// checkJSON checks that v marshals to want, and that want unmarshals to a
+// value which marshals to want again.
+func checkJSON(t *testing.T, v interface{}, want string) {
+	t.Helper()
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != want {
+		t.Errorf("json.Marshal: got %s, want %s", b, want)
+	}
+	p := reflect.New(reflect.TypeOf(v))
+	if err = json.Unmarshal([]byte(want), p.Interface()); err != nil {
+		t.Fatal(err)
+	}
+	if b, err = json.Marshal(p.Elem().Interface()); err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != want {
+		t.Errorf("json round trip: got %s, want %s", b, want)
+	}
+}
+
+// checkYAML is as checkJSON for YAML, ignoring the trailing newline.
+func checkYAML(t *testing.T, v interface{}, want string) {
+	t.Helper()
+	b, err := yaml.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.TrimSuffix(string(b), "\n"); got != want {
+		t.Errorf("yaml.Marshal: got %s, want %s", got, want)
+	}
+	p := reflect.New(reflect.TypeOf(v))
+	if err = yaml.Unmarshal([]byte(want), p.Interface()); err != nil {
+		t.Fatal(err)
+	}
+	if b, err = yaml.Marshal(p.Elem().Interface()); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.TrimSuffix(string(b), "\n"); got != want {
+		t.Errorf("yaml round trip: got %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,188 @@
+/*
+ * Copyright 2018 Farsight Security, Inc.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package config
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// HTTPClient contains the configuration for an HTTP API client as it
+// appears in a JSON or YAML config, e.g.:
+//
+//      api:
+//        baseURL: https://api.example.com/v1/
+//        tls:
+//          rootCAFiles: [/etc/app/ca.pem]
+//          certificates:
+//            - certFile: /etc/app/client.pem
+//              keyFile: /etc/app/client.key
+//        timeout: 30s
+//        retry:
+//          initial: 500ms
+//          max: 5s
+//          maxAttempts: 4
+//        headers:
+//          X-API-Key: $API_KEY
+//
+// Header values are Strings, so secrets such as API keys may be read from
+// the environment or a file.
+//
+// If Retry is set, requests which fail with a network error or a 429, 502,
+// 503, or 504 status are retried with delays given by the Backoff, if
+// their method is idempotent and their body, if any, can be replayed. If
+// the Backoff's MaxAttempts is not set, a request is attempted at most 3
+// times.
+type HTTPClient struct {
+	BaseURL               *URL                `json:"baseURL,omitempty" yaml:"baseURL,omitempty"`
+	TLS                   *TLS                `json:"tls,omitempty" yaml:"tls,omitempty"`
+	Proxy                 *URL                `json:"proxy,omitempty" yaml:"proxy,omitempty"`
+	Timeout               Duration            `json:"timeout,omitempty" yaml:"timeout,omitempty"`
+	DialTimeout           Duration            `json:"dialTimeout,omitempty" yaml:"dialTimeout,omitempty"`
+	KeepAlive             Duration            `json:"keepAlive,omitempty" yaml:"keepAlive,omitempty"`
+	TLSHandshakeTimeout   Duration            `json:"tlsHandshakeTimeout,omitempty" yaml:"tlsHandshakeTimeout,omitempty"`
+	ResponseHeaderTimeout Duration            `json:"responseHeaderTimeout,omitempty" yaml:"responseHeaderTimeout,omitempty"`
+	IdleConnTimeout       Duration            `json:"idleConnTimeout,omitempty" yaml:"idleConnTimeout,omitempty"`
+	MaxIdleConns          int                 `json:"maxIdleConns,omitempty" yaml:"maxIdleConns,omitempty"`
+	MaxIdleConnsPerHost   int                 `json:"maxIdleConnsPerHost,omitempty" yaml:"maxIdleConnsPerHost,omitempty"`
+	DisableHTTP2          bool                `json:"disableHTTP2,omitempty" yaml:"disableHTTP2,omitempty"`
+	Retry                 *Backoff            `json:"retry,omitempty" yaml:"retry,omitempty"`
+	Headers               Map[string, String] `json:"headers,omitempty" yaml:"headers,omitempty"`
+}
+
+// Transport returns an http.Transport configured with the HTTPClient's
+// connection, TLS, and proxy settings.
+func (c HTTPClient) Transport() *http.Transport {
+	d := Dialer{
+		Timeout:   c.DialTimeout,
+		KeepAlive: c.KeepAlive,
+		TLS:       c.TLS,
+		Proxy:     c.Proxy,
+	}
+	t := d.Transport()
+	if c.TLSHandshakeTimeout.Duration > 0 {
+		t.TLSHandshakeTimeout = c.TLSHandshakeTimeout.Duration
+	}
+	t.ResponseHeaderTimeout = c.ResponseHeaderTimeout.Duration
+	t.IdleConnTimeout = c.IdleConnTimeout.Duration
+	t.MaxIdleConns = c.MaxIdleConns
+	t.MaxIdleConnsPerHost = c.MaxIdleConnsPerHost
+	if c.DisableHTTP2 {
+		t.ForceAttemptHTTP2 = false
+		t.TLSNextProto = make(map[string]func(string, *tls.Conn) http.RoundTripper)
+	}
+	return t
+}
+
+// Client returns an http.Client using the HTTPClient's Transport, which
+// adds the configured headers to requests not already having them and
+// retries failed requests as configured.
+func (c HTTPClient) Client() *http.Client {
+	rt := &clientTransport{
+		base:   c.Transport(),
+		header: make(http.Header),
+	}
+	if c.Retry != nil {
+		rt.retry = *c.Retry
+		if rt.retry.MaxAttempts == 0 {
+			rt.retry.MaxAttempts = defaultRetryAttempts
+		}
+	}
+	for k, v := range c.Headers.Values {
+		rt.header.Set(k, v.String())
+	}
+	return &http.Client{Transport: rt, Timeout: c.Timeout.Duration}
+}
+
+// ResolveReference resolves ref relative to the configured BaseURL. If no
+// BaseURL is configured, ref is returned parsed as is.
+func (c HTTPClient) ResolveReference(ref string) (*url.URL, error) {
+	u, err := url.Parse(ref)
+	if err != nil || c.BaseURL == nil || c.BaseURL.URL == nil {
+		return u, err
+	}
+	return c.BaseURL.ResolveReference(u), nil
+}
+
+// NewRequest returns an http.Request for ref resolved relative to the
+// configured BaseURL.
+func (c HTTPClient) NewRequest(ctx context.Context, method, ref string, body io.Reader) (*http.Request, error) {
+	u, err := c.ResolveReference(ref)
+	if err != nil {
+		return nil, err
+	}
+	return http.NewRequestWithContext(ctx, method, u.String(), body)
+}
+
+const defaultRetryAttempts = 3
+
+type clientTransport struct {
+	base   http.RoundTripper
+	header http.Header
+	retry  Backoff
+}
+
+func (t *clientTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	for k, v := range t.header {
+		if _, ok := req.Header[k]; !ok {
+			req.Header[k] = v
+		}
+	}
+
+	for attempt := 1; ; attempt++ {
+		resp, err := t.base.RoundTrip(req)
+		delay, ok := t.retry.Next(attempt)
+		if !ok || !t.retry.IsSet() || !t.retryable(req, resp, err) {
+			return resp, err
+		}
+		if resp != nil {
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+		if req.Body != nil && req.Body != http.NoBody {
+			if req.Body, err = req.GetBody(); err != nil {
+				return nil, err
+			}
+		}
+		if delay > 0 {
+			timer := time.NewTimer(delay)
+			select {
+			case <-req.Context().Done():
+				timer.Stop()
+				return nil, req.Context().Err()
+			case <-timer.C:
+			}
+		}
+	}
+}
+
+func (t *clientTransport) retryable(req *http.Request, resp *http.Response, err error) bool {
+	switch req.Method {
+	case "GET", "HEAD", "OPTIONS", "PUT", "DELETE", "TRACE":
+	default:
+		return false
+	}
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return false
+	}
+	if err != nil {
+		return req.Context().Err() == nil
+	}
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
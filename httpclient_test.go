@@ -0,0 +1,106 @@
+/*
+ * Copyright 2018 Farsight Security, Inc.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package config
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+func TestHTTPClientRequest(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Path", r.URL.Path)
+		w.Header().Set("X-Key", r.Header.Get("X-Api-Key"))
+	}))
+	defer srv.Close()
+
+	var c HTTPClient
+	checkOK(t, yaml.Unmarshal([]byte(`
+baseURL: `+srv.URL+`/v1/
+timeout: 5s
+headers:
+  X-API-Key: secret
+`), &c), c.BaseURL != nil)
+
+	u, err := c.ResolveReference("items/1")
+	checkOK(t, err, u.String() == srv.URL+"/v1/items/1")
+
+	req, err := c.NewRequest(context.Background(), "GET", "items", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("X-Other", "x")
+	resp, err := c.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	checkOK(t, nil, resp.Header.Get("X-Path") == "/v1/items" &&
+		resp.Header.Get("X-Key") == "secret")
+
+	// Headers set on the request take precedence.
+	req, _ = c.NewRequest(context.Background(), "GET", "items", nil)
+	req.Header.Set("X-API-Key", "other")
+	resp, err = c.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	checkOK(t, nil, resp.Header.Get("X-Key") == "other")
+}
+
+func TestHTTPClientRetry(t *testing.T) {
+	var n int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&n, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+
+	var c HTTPClient
+	checkOK(t, yaml.Unmarshal([]byte("retry: initial=1ms,maxAttempts=4\n"), &c),
+		c.Retry != nil && c.Retry.MaxAttempts == 4)
+	resp, err := c.Client().Get(srv.URL)
+	checkOK(t, err, resp.StatusCode == http.StatusOK && atomic.LoadInt32(&n) == 3)
+	resp.Body.Close()
+
+	// Requests which are not idempotent are not retried.
+	atomic.StoreInt32(&n, 0)
+	resp, err = c.Client().Post(srv.URL, "text/plain", strings.NewReader("x"))
+	checkOK(t, err, resp.StatusCode == http.StatusServiceUnavailable && atomic.LoadInt32(&n) == 1)
+	resp.Body.Close()
+
+	// Replayable bodies are resent.
+	atomic.StoreInt32(&n, 0)
+	req, _ := http.NewRequest("PUT", srv.URL, strings.NewReader("x"))
+	resp, err = c.Client().Do(req)
+	checkOK(t, err, resp.StatusCode == http.StatusOK && atomic.LoadInt32(&n) == 3)
+	resp.Body.Close()
+
+	// Without MaxAttempts, a request is attempted three times.
+	c.Retry.MaxAttempts = 0
+	atomic.StoreInt32(&n, -10)
+	resp, err = c.Client().Get(srv.URL)
+	checkOK(t, err, resp.StatusCode == http.StatusServiceUnavailable && atomic.LoadInt32(&n) == -7)
+	resp.Body.Close()
+
+	// Without retries, the first failure is returned.
+	c.Retry = nil
+	atomic.StoreInt32(&n, 0)
+	resp, err = c.Client().Get(srv.URL)
+	checkOK(t, err, resp.StatusCode == http.StatusServiceUnavailable)
+	resp.Body.Close()
+}
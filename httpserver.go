@@ -0,0 +1,143 @@
+/*
+ * Copyright 2018 Farsight Security, Inc.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package config
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"net"
+	"net/http"
+)
+
+// HTTPServer contains the configuration for an HTTP server as it appears
+// in a JSON or YAML config, e.g.:
+//
+//      http:
+//        addr: :8443
+//        tls:
+//          certificates:
+//            - certFile: /etc/app/cert.pem
+//              keyFile: /etc/app/key.pem
+//        readHeaderTimeout: 5s
+//        idleTimeout: 2m
+//        maxHeaderBytes: 64KiB
+//        shutdownTimeout: 30s
+//
+// The address is a DefaultAddr whose network defaults to "tcp". If TLS is
+// present, the server accepts TLS connections, with HTTP/2 enabled.
+// Timeouts left unset have the http.Server defaults.
+type HTTPServer struct {
+	Addr              DefaultAddr `json:"addr" yaml:"addr"`
+	TLS               *TLS        `json:"tls,omitempty" yaml:"tls,omitempty"`
+	ReadTimeout       Duration    `json:"readTimeout,omitempty" yaml:"readTimeout,omitempty"`
+	ReadHeaderTimeout Duration    `json:"readHeaderTimeout,omitempty" yaml:"readHeaderTimeout,omitempty"`
+	WriteTimeout      Duration    `json:"writeTimeout,omitempty" yaml:"writeTimeout,omitempty"`
+	IdleTimeout       Duration    `json:"idleTimeout,omitempty" yaml:"idleTimeout,omitempty"`
+	MaxHeaderBytes    ByteSize    `json:"maxHeaderBytes,omitempty" yaml:"maxHeaderBytes,omitempty"`
+	ShutdownTimeout   Duration    `json:"shutdownTimeout,omitempty" yaml:"shutdownTimeout,omitempty"`
+}
+
+type httpServer HTTPServer
+
+// UnmarshalJSON satisfies the json.Unmarshaler interface
+func (c *HTTPServer) UnmarshalJSON(b []byte) error {
+	c.Addr.DefaultNetwork = "tcp"
+	return json.Unmarshal(b, (*httpServer)(c))
+}
+
+// UnmarshalYAML satisfies the yaml.Unmarshaler interface
+func (c *HTTPServer) UnmarshalYAML(u func(interface{}) error) error {
+	c.Addr.DefaultNetwork = "tcp"
+	return u((*httpServer)(c))
+}
+
+func (c HTTPServer) tlsConfig() *tls.Config {
+	if c.TLS == nil || c.TLS.Config == nil {
+		return nil
+	}
+	tc := c.TLS.Config.Clone()
+	if len(tc.NextProtos) == 0 {
+		tc.NextProtos = []string{"h2", "http/1.1"}
+	}
+	return tc
+}
+
+// Server returns an http.Server serving h with the configured timeouts,
+// header size limit, and TLS configuration.
+func (c HTTPServer) Server(h http.Handler) *http.Server {
+	srv := &http.Server{
+		Handler:           h,
+		TLSConfig:         c.tlsConfig(),
+		ReadTimeout:       c.ReadTimeout.Duration,
+		ReadHeaderTimeout: c.ReadHeaderTimeout.Duration,
+		WriteTimeout:      c.WriteTimeout.Duration,
+		IdleTimeout:       c.IdleTimeout.Duration,
+		MaxHeaderBytes:    int(c.MaxHeaderBytes.Bytes),
+	}
+	if c.Addr.IsSet() {
+		srv.Addr = c.Addr.String()
+	}
+	return srv
+}
+
+// Listen opens a listener on the configured address, accepting TLS
+// connections if TLS is configured.
+func (c HTTPServer) Listen() (net.Listener, error) {
+	l, err := c.Addr.Listen()
+	if err != nil {
+		return nil, err
+	}
+	if tc := c.tlsConfig(); tc != nil {
+		l = tls.NewListener(l, tc)
+	}
+	return l, nil
+}
+
+// NewServer returns an http.Server serving h as with Server, and a listener
+// for it as with Listen. The caller runs the server with srv.Serve(l).
+func (c HTTPServer) NewServer(h http.Handler) (*http.Server, net.Listener, error) {
+	l, err := c.Listen()
+	if err != nil {
+		return nil, nil, err
+	}
+	return c.Server(h), l, nil
+}
+
+// Shutdown gracefully shuts down srv, waiting up to the configured
+// ShutdownTimeout for active connections to finish, or indefinitely if
+// no timeout is configured.
+func (c HTTPServer) Shutdown(srv *http.Server) error {
+	ctx := context.Background()
+	if c.ShutdownTimeout.Duration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.ShutdownTimeout.Duration)
+		defer cancel()
+	}
+	return srv.Shutdown(ctx)
+}
+
+// ListenAndServe serves h on the configured address until ctx is done,
+// then shuts the server down gracefully as with Shutdown.
+func (c HTTPServer) ListenAndServe(ctx context.Context, h http.Handler) error {
+	srv, l, err := c.NewServer(h)
+	if err != nil {
+		return err
+	}
+	errc := make(chan error, 1)
+	go func() { errc <- srv.Serve(l) }()
+	select {
+	case err = <-errc:
+		return err
+	case <-ctx.Done():
+	}
+	err = c.Shutdown(srv)
+	<-errc
+	return err
+}
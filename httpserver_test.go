@@ -0,0 +1,86 @@
+/*
+ * Copyright 2018 Farsight Security, Inc.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+func TestHTTPServerUnmarshal(t *testing.T) {
+	var c HTTPServer
+	err := yaml.Unmarshal([]byte(`
+addr: 127.0.0.1:8080
+readHeaderTimeout: 5s
+idleTimeout: 2m
+maxHeaderBytes: 64KiB
+shutdownTimeout: 30s
+`), &c)
+	checkOK(t, err, c.Addr.Network() == "tcp" && c.Addr.String() == "127.0.0.1:8080")
+
+	srv := c.Server(http.NotFoundHandler())
+	checkOK(t, nil, srv.Addr == "127.0.0.1:8080" &&
+		srv.ReadHeaderTimeout == 5*time.Second &&
+		srv.IdleTimeout == 2*time.Minute &&
+		srv.ReadTimeout == 0 &&
+		srv.MaxHeaderBytes == 64<<10 &&
+		srv.TLSConfig == nil)
+
+	var j HTTPServer
+	checkOK(t, json.Unmarshal([]byte(`{"addr":"unix:/run/app.sock"}`), &j),
+		j.Addr.Network() == "unix")
+	checkErr(t, json.Unmarshal([]byte(`{"addr":"localhost"}`), &j))
+	checkErr(t, json.Unmarshal([]byte(`{"addr":"tcp:127.0.0.1:80","idleTimeout":"x"}`), &j))
+}
+
+func TestHTTPServerListenAndServe(t *testing.T) {
+	var c HTTPServer
+	checkOK(t, json.Unmarshal([]byte(`{"addr":"127.0.0.1:0","shutdownTimeout":"5s"}`), &c), true)
+
+	srv, l, err := c.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "ok")
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	errc := make(chan error, 1)
+	go func() { errc <- srv.Serve(l) }()
+
+	resp, err := http.Get("http://" + l.Addr().String() + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	checkOK(t, err, string(b) == "ok")
+
+	checkOK(t, c.Shutdown(srv), true)
+	checkOK(t, nil, <-errc == http.ErrServerClosed)
+}
+
+func TestHTTPServerContextShutdown(t *testing.T) {
+	var c HTTPServer
+	checkOK(t, c.Addr.Set("tcp:127.0.0.1:0"), true)
+	ctx, cancel := context.WithCancel(context.Background())
+	errc := make(chan error, 1)
+	go func() { errc <- c.ListenAndServe(ctx, http.NotFoundHandler()) }()
+	cancel()
+	select {
+	case err := <-errc:
+		checkOK(t, err, true)
+	case <-time.After(5 * time.Second):
+		t.Fatal("ListenAndServe did not return")
+	}
+}
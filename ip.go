@@ -0,0 +1,378 @@
+/*
+ * Copyright 2018 Farsight Security, Inc.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"net"
+	"net/netip"
+	"sort"
+	"strings"
+)
+
+// IP provides JSON and YAML Marshaling and Unmarshaling of single IPv4 or
+// IPv6 addresses, internally representing them as netip.Addr.
+type IP struct{ netip.Addr }
+
+// Set satisfies flag.Value for use in command line arguments. The empty
+// string leaves the IP unset.
+func (ip *IP) Set(s string) (err error) {
+	if s = strings.TrimSpace(s); s == "" {
+		ip.Addr = netip.Addr{}
+		return nil
+	}
+	ip.Addr, err = netip.ParseAddr(s)
+	return
+}
+
+// String satisfies the flag.Value interface. It returns the empty string
+// if the IP is unset.
+func (ip IP) String() string {
+	if !ip.IsValid() {
+		return ""
+	}
+	return ip.Addr.String()
+}
+
+// IsSet returns true if the IP was assigned a value with Set or by
+// unmarshaling.
+func (ip IP) IsSet() bool {
+	return ip.IsValid()
+}
+
+// UnmarshalJSON satisfies the json.Unmarshaler interface
+func (ip *IP) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	return ip.Set(s)
+}
+
+// UnmarshalYAML satisfies the yaml.Unmarshaler interface
+func (ip *IP) UnmarshalYAML(u func(interface{}) error) error {
+	var s string
+	if err := u(&s); err != nil {
+		return err
+	}
+	return ip.Set(s)
+}
+
+// MarshalJSON satisfies the json.Marshaler interface
+func (ip IP) MarshalJSON() ([]byte, error) {
+	return json.Marshal(ip.String())
+}
+
+// MarshalYAML satisfies the yaml.Marshaler interface
+func (ip IP) MarshalYAML() (interface{}, error) {
+	return ip.String(), nil
+}
+
+// Prefix provides JSON and YAML Marshaling and Unmarshaling of IP network
+// prefixes in CIDR notation, e.g., "192.0.2.0/24" or "2001:db8::/32",
+// internally representing them as netip.Prefix.
+type Prefix struct{ netip.Prefix }
+
+// Set satisfies flag.Value for use in command line arguments. The empty
+// string leaves the Prefix unset.
+func (p *Prefix) Set(s string) (err error) {
+	if s = strings.TrimSpace(s); s == "" {
+		p.Prefix = netip.Prefix{}
+		return nil
+	}
+	p.Prefix, err = netip.ParsePrefix(s)
+	return
+}
+
+// String satisfies the flag.Value interface. It returns the empty string
+// if the Prefix is unset.
+func (p Prefix) String() string {
+	if !p.IsValid() {
+		return ""
+	}
+	return p.Prefix.String()
+}
+
+// IsSet returns true if the Prefix was assigned a value with Set or by
+// unmarshaling.
+func (p Prefix) IsSet() bool {
+	return p.IsValid()
+}
+
+// UnmarshalJSON satisfies the json.Unmarshaler interface
+func (p *Prefix) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	return p.Set(s)
+}
+
+// UnmarshalYAML satisfies the yaml.Unmarshaler interface
+func (p *Prefix) UnmarshalYAML(u func(interface{}) error) error {
+	var s string
+	if err := u(&s); err != nil {
+		return err
+	}
+	return p.Set(s)
+}
+
+// MarshalJSON satisfies the json.Marshaler interface
+func (p Prefix) MarshalJSON() ([]byte, error) {
+	return json.Marshal(p.String())
+}
+
+// MarshalYAML satisfies the yaml.Marshaler interface
+func (p Prefix) MarshalYAML() (interface{}, error) {
+	return p.String(), nil
+}
+
+// IPNet provides JSON and YAML Marshaling and Unmarshaling of IP networks
+// in CIDR notation, internally representing them as *net.IPNet from net.
+type IPNet struct{ *net.IPNet }
+
+// Set satisfies flag.Value for use in command line arguments. The empty
+// string leaves the IPNet unset.
+func (n *IPNet) Set(s string) (err error) {
+	if s = strings.TrimSpace(s); s == "" {
+		n.IPNet = nil
+		return nil
+	}
+	_, n.IPNet, err = net.ParseCIDR(s)
+	return
+}
+
+// String satisfies the flag.Value interface. It returns the empty string
+// if the IPNet is unset.
+func (n IPNet) String() string {
+	if n.IPNet == nil {
+		return ""
+	}
+	return n.IPNet.String()
+}
+
+// IsSet returns true if the IPNet was assigned a value with Set or by
+// unmarshaling.
+func (n IPNet) IsSet() bool {
+	return n.IPNet != nil
+}
+
+// UnmarshalJSON satisfies the json.Unmarshaler interface
+func (n *IPNet) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	return n.Set(s)
+}
+
+// UnmarshalYAML satisfies the yaml.Unmarshaler interface
+func (n *IPNet) UnmarshalYAML(u func(interface{}) error) error {
+	var s string
+	if err := u(&s); err != nil {
+		return err
+	}
+	return n.Set(s)
+}
+
+// MarshalJSON satisfies the json.Marshaler interface
+func (n IPNet) MarshalJSON() ([]byte, error) {
+	return json.Marshal(n.String())
+}
+
+// MarshalYAML satisfies the yaml.Marshaler interface
+func (n IPNet) MarshalYAML() (interface{}, error) {
+	return n.String(), nil
+}
+
+// IPSet is a set of IP addresses given as a list of networks in CIDR
+// notation and single addresses, e.g., for access control lists:
+//
+//      allowed: [192.0.2.0/24, 198.51.100.7, "2001:db8::/32"]
+//
+// In JSON and YAML, an IPSet is a sequence of strings or a single
+// comma-separated string. As a flag.Value, it accepts comma-separated or
+// repeated values with the same semantics as List.
+//
+// An IPSet marshals to a normalized list, with host bits cleared, networks
+// contained in other networks removed, and the remainder sorted.
+type IPSet struct {
+	prefixes  []netip.Prefix
+	ranges    []ipRange
+	set, flag bool
+}
+
+type ipRange struct{ lo, hi netip.Addr }
+
+func parseIPSetEntry(s string) (netip.Prefix, error) {
+	s = strings.TrimSpace(s)
+	if strings.Contains(s, "/") {
+		p, err := netip.ParsePrefix(s)
+		return p.Masked(), err
+	}
+	a, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	a = a.Unmap()
+	return netip.PrefixFrom(a, a.BitLen()), nil
+}
+
+func (set *IPSet) add(entries []string) error {
+	prefixes := make([]netip.Prefix, 0, len(entries))
+	for _, e := range entries {
+		p, err := parseIPSetEntry(e)
+		if err != nil {
+			return err
+		}
+		prefixes = append(prefixes, p)
+	}
+	if set.flag {
+		prefixes = append(set.prefixes, prefixes...)
+	}
+	set.build(prefixes)
+	set.set = true
+	return nil
+}
+
+func (set *IPSet) build(prefixes []netip.Prefix) {
+	sort.Slice(prefixes, func(i, j int) bool {
+		if c := prefixes[i].Addr().Compare(prefixes[j].Addr()); c != 0 {
+			return c < 0
+		}
+		return prefixes[i].Bits() < prefixes[j].Bits()
+	})
+
+	var kept []netip.Prefix
+	var ranges []ipRange
+	for _, p := range prefixes {
+		if n := len(kept); n > 0 && kept[n-1].Contains(p.Addr()) {
+			continue
+		}
+		kept = append(kept, p)
+
+		r := ipRange{p.Addr(), lastAddr(p)}
+		if n := len(ranges); n > 0 {
+			last := &ranges[n-1]
+			if next := last.hi.Next(); next.IsValid() && next == r.lo {
+				last.hi = r.hi
+				continue
+			}
+		}
+		ranges = append(ranges, r)
+	}
+	set.prefixes, set.ranges = kept, ranges
+}
+
+func lastAddr(p netip.Prefix) netip.Addr {
+	b := p.Addr().AsSlice()
+	for i := p.Bits(); i < len(b)*8; i++ {
+		b[i/8] |= 0x80 >> uint(i%8)
+	}
+	a, _ := netip.AddrFromSlice(b)
+	return a
+}
+
+// Contains returns true if the address ip is in the IPSet.
+func (set *IPSet) Contains(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	i := sort.Search(len(set.ranges), func(i int) bool {
+		return set.ranges[i].hi.Compare(ip) >= 0
+	})
+	return i < len(set.ranges) && set.ranges[i].lo.Compare(ip) <= 0
+}
+
+// ContainsIP returns true if the net.IP ip is in the IPSet.
+func (set *IPSet) ContainsIP(ip net.IP) bool {
+	a, ok := netip.AddrFromSlice(ip)
+	return ok && set.Contains(a)
+}
+
+// Prefixes returns the normalized list of networks in the IPSet.
+func (set *IPSet) Prefixes() []netip.Prefix {
+	return append([]netip.Prefix(nil), set.prefixes...)
+}
+
+// IsSet returns true if the IPSet was assigned a value with Set or by
+// unmarshaling.
+func (set *IPSet) IsSet() bool {
+	return set.set
+}
+
+func (set *IPSet) strings() []string {
+	s := make([]string, len(set.prefixes))
+	for i, p := range set.prefixes {
+		if p.IsSingleIP() {
+			s[i] = p.Addr().String()
+		} else {
+			s[i] = p.String()
+		}
+	}
+	return s
+}
+
+// String satisfies the flag.Value interface, returning the normalized
+// networks in the IPSet separated by commas.
+func (set *IPSet) String() string {
+	if set == nil {
+		return ""
+	}
+	return strings.Join(set.strings(), ",")
+}
+
+// Set satisfies the flag.Value interface.
+func (set *IPSet) Set(s string) error {
+	if err := set.add(splitList(strings.TrimSpace(s))); err != nil {
+		return err
+	}
+	set.flag = true
+	return nil
+}
+
+// MarshalJSON satisfies the json.Marshaler interface
+func (set IPSet) MarshalJSON() ([]byte, error) {
+	return json.Marshal(set.strings())
+}
+
+// MarshalYAML satisfies the yaml.Marshaler interface
+func (set IPSet) MarshalYAML() (interface{}, error) {
+	return set.strings(), nil
+}
+
+// UnmarshalJSON satisfies the json.Unmarshaler interface
+func (set *IPSet) UnmarshalJSON(b []byte) error {
+	set.flag = false
+	if b = bytes.TrimSpace(b); len(b) > 0 && b[0] == '"' {
+		var s string
+		if err := json.Unmarshal(b, &s); err != nil {
+			return err
+		}
+		return set.add(splitList(strings.TrimSpace(s)))
+	}
+	var l []string
+	if err := json.Unmarshal(b, &l); err != nil {
+		return err
+	}
+	return set.add(l)
+}
+
+// UnmarshalYAML satisfies the yaml.Unmarshaler interface
+func (set *IPSet) UnmarshalYAML(u func(interface{}) error) error {
+	set.flag = false
+	var l []string
+	if err := u(&l); err != nil {
+		var s string
+		if u(&s) != nil {
+			return err
+		}
+		return set.add(splitList(strings.TrimSpace(s)))
+	}
+	return set.add(l)
+}
@@ -0,0 +1,75 @@
+/*
+ * Copyright 2018 Farsight Security, Inc.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package config
+
+import (
+	"encoding/json"
+	"net"
+	"net/netip"
+	"testing"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+func TestIP(t *testing.T) {
+	var ip IP
+	checkOK(t, nil, !ip.IsSet() && ip.String() == "")
+	checkOK(t, ip.Set(" 192.0.2.1 "), ip.IsSet() && ip.String() == "192.0.2.1")
+	checkOK(t, ip.Set(""), !ip.IsSet())
+	checkErr(t, ip.Set("192.0.2.256"))
+	checkJSON(t, IP{netip.MustParseAddr("2001:db8::1")}, `"2001:db8::1"`)
+	checkJSON(t, IP{}, `""`)
+	checkYAML(t, IP{}, `""`)
+}
+
+func TestPrefix(t *testing.T) {
+	var p Prefix
+	checkOK(t, p.Set("192.0.2.0/24"), p.IsSet() && p.Bits() == 24)
+	checkOK(t, p.Set(""), !p.IsSet())
+	checkErr(t, p.Set("192.0.2.0/33"))
+	checkJSON(t, Prefix{netip.MustParsePrefix("2001:db8::/32")}, `"2001:db8::/32"`)
+	checkJSON(t, Prefix{}, `""`)
+	checkYAML(t, Prefix{}, `""`)
+}
+
+func TestIPNet(t *testing.T) {
+	var n IPNet
+	checkOK(t, nil, !n.IsSet() && n.String() == "")
+	checkOK(t, n.Set("192.0.2.7/24"), n.IsSet() && n.String() == "192.0.2.0/24")
+	checkOK(t, nil, n.Contains(net.ParseIP("192.0.2.200")))
+	checkOK(t, n.Set(""), !n.IsSet())
+	checkErr(t, n.Set("192.0.2.7"))
+
+	_, ipn, _ := net.ParseCIDR("2001:db8::/32")
+	checkJSON(t, IPNet{ipn}, `"2001:db8::/32"`)
+	checkJSON(t, IPNet{}, `""`)
+	checkYAML(t, IPNet{}, `""`)
+	checkOK(t, json.Unmarshal([]byte(`""`), &n), !n.IsSet())
+	checkErr(t, yaml.Unmarshal([]byte(`x`), &n))
+}
+
+func TestIPSet(t *testing.T) {
+	var set IPSet
+	checkOK(t, nil, !set.IsSet())
+	checkOK(t, set.Set("192.0.2.0/24, 192.0.2.7, 198.51.100.7"), set.IsSet())
+	checkOK(t, nil, set.String() == "192.0.2.0/24,198.51.100.7")
+	checkOK(t, set.Set("2001:db8::/32"), len(set.Prefixes()) == 3)
+	checkOK(t, nil, set.Contains(netip.MustParseAddr("192.0.2.255")))
+	checkOK(t, nil, set.Contains(netip.MustParseAddr("::ffff:198.51.100.7")))
+	checkOK(t, nil, !set.Contains(netip.MustParseAddr("198.51.100.8")))
+	checkOK(t, nil, set.ContainsIP(net.ParseIP("2001:db8::1")))
+	checkErr(t, set.Set("x"))
+
+	var c struct{ Allowed IPSet }
+	checkOK(t, yaml.Unmarshal([]byte(`allowed: [10.0.0.0/9, 10.128.0.0/9, 10.1.2.3]`), &c),
+		c.Allowed.Contains(netip.MustParseAddr("10.200.0.1")))
+	checkOK(t, json.Unmarshal([]byte(`{"Allowed":"10.0.0.1,10.0.0.2"}`), &c),
+		c.Allowed.String() == "10.0.0.1,10.0.0.2")
+	checkJSON(t, c.Allowed, `["10.0.0.1","10.0.0.2"]`)
+}
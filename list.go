@@ -0,0 +1,217 @@
+/*
+ * Copyright 2018 Farsight Security, Inc.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+)
+
+// List is a list of values of type T, which may be any of the types in this
+// package or a primitive type. In JSON and YAML, a List is a sequence of
+// the element type's representation, or a single comma-separated string.
+//
+// As a flag.Value, a List accepts comma-separated values, repeated flags,
+// or both:
+//
+//      -upstream tcp:a:1 -upstream tcp:b:2,tcp:c:3
+//
+// The first call to Set replaces any existing (default or unmarshaled)
+// values, subsequent calls append to them. Each element is parsed by the
+// element type's Set method. The same format can be loaded from the
+// environment with env.Var, e.g., UPSTREAMS=tcp:a:1,tcp:b:2.
+//
+// A comma within an element is written as "\,", e.g., in a regular
+// expression "a{1\,3}" or a glob "*.{go\,mod}".
+//
+// Elem, if set before Set or unmarshaling, is the starting value of each
+// element, so that options of the element type apply to every element,
+// e.g.:
+//
+//      Upstreams: config.List[config.TCPHost]{
+//              Elem: config.TCPHost{DefaultPort: "53"},
+//      }
+type List[T any] struct {
+	Values    []T
+	Elem      T
+	set, flag bool
+}
+
+func (l *List[T]) elems() reflect.Value {
+	return reflect.ValueOf(&l.Values).Elem()
+}
+
+// IsSet returns true if the List was assigned a value with Set or by
+// unmarshaling.
+func (l List[T]) IsSet() bool {
+	return l.set
+}
+
+// Len returns the number of values in the List.
+func (l List[T]) Len() int {
+	return len(l.Values)
+}
+
+// String satisfies the flag.Value interface, returning the List's
+// values separated by commas.
+func (l *List[T]) String() string {
+	if l == nil {
+		return ""
+	}
+	s := make([]string, len(l.Values))
+	for i := range l.Values {
+		s[i] = escapeList(formatValue(&l.Values[i]))
+	}
+	return strings.Join(s, ",")
+}
+
+// Set satisfies the flag.Value interface.
+func (l *List[T]) Set(s string) error {
+	var vals []T
+	for _, e := range splitList(strings.TrimSpace(s)) {
+		v := l.Elem
+		if err := setValue(&v, strings.TrimSpace(e)); err != nil {
+			return err
+		}
+		vals = append(vals, v)
+	}
+	if !l.flag {
+		l.Values = nil
+	}
+	l.Values = append(l.Values, vals...)
+	l.set, l.flag = true, true
+	return nil
+}
+
+// splitList splits s at commas, except those escaped as "\,", which are
+// unescaped. Other backslashes are left as they are.
+func splitList(s string) []string {
+	if s == "" {
+		return nil
+	}
+	var list []string
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\\' && i+1 < len(s) && s[i+1] == ',':
+			b.WriteByte(',')
+			i++
+		case s[i] == ',':
+			list = append(list, b.String())
+			b.Reset()
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return append(list, b.String())
+}
+
+// escapeList escapes the commas in s for splitList.
+func escapeList(s string) string {
+	return strings.ReplaceAll(s, ",", `\,`)
+}
+
+func (l *List[T]) unmarshaled(vals []T) {
+	l.Values = vals
+	l.set, l.flag = true, false
+}
+
+// MarshalJSON satisfies the json.Marshaler interface
+func (l List[T]) MarshalJSON() ([]byte, error) {
+	if l.Values == nil {
+		return []byte("[]"), nil
+	}
+	return json.Marshal(l.Values)
+}
+
+// MarshalYAML satisfies the yaml.Marshaler interface
+func (l List[T]) MarshalYAML() (interface{}, error) {
+	vals := make([]interface{}, len(l.Values))
+	for i := range l.Values {
+		vals[i] = &l.Values[i]
+	}
+	return vals, nil
+}
+
+// UnmarshalJSON satisfies the json.Unmarshaler interface
+func (l *List[T]) UnmarshalJSON(b []byte) error {
+	if b = bytes.TrimSpace(b); len(b) > 0 && b[0] == '"' {
+		var s string
+		if err := json.Unmarshal(b, &s); err != nil {
+			return err
+		}
+		l.flag = false
+		return l.Set(s)
+	}
+	var raw []json.RawMessage
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+	var vals []T
+	if raw != nil {
+		vals = make([]T, len(raw))
+	}
+	for i := range raw {
+		vals[i] = l.Elem
+		if err := json.Unmarshal(raw[i], &vals[i]); err != nil {
+			return err
+		}
+	}
+	l.unmarshaled(vals)
+	return nil
+}
+
+// UnmarshalYAML satisfies the yaml.Unmarshaler interface
+func (l *List[T]) UnmarshalYAML(u func(interface{}) error) error {
+	var raw []yamlElem
+	if err := u(&raw); err != nil {
+		var s string
+		if u(&s) != nil {
+			return err
+		}
+		l.flag = false
+		return l.Set(s)
+	}
+	var vals []T
+	if raw != nil {
+		vals = make([]T, len(raw))
+	}
+	for i := range raw {
+		vals[i] = l.Elem
+		if err := raw[i].unmarshal(&vals[i]); err != nil {
+			return err
+		}
+	}
+	l.unmarshaled(vals)
+	return nil
+}
+
+// yamlElem holds the unmarshal function for one element of a YAML sequence
+// or mapping, so that the element can be decoded into a value prepared
+// beforehand. It must be used before the UnmarshalYAML call which decoded
+// it returns.
+type yamlElem struct {
+	u func(interface{}) error
+}
+
+func (e *yamlElem) UnmarshalYAML(u func(interface{}) error) error {
+	e.u = u
+	return nil
+}
+
+// unmarshal decodes the element into v, leaving v unchanged if the element
+// is null.
+func (e yamlElem) unmarshal(v interface{}) error {
+	if e.u == nil {
+		return nil
+	}
+	return e.u(v)
+}
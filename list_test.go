@@ -0,0 +1,75 @@
+/*
+ * Copyright 2018 Farsight Security, Inc.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package config
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+func TestSplitList(t *testing.T) {
+	for s, want := range map[string][]string{
+		"":          nil,
+		"a":         {"a"},
+		"a,b":       {"a", "b"},
+		"a,,b":      {"a", "", "b"},
+		`a\,b,c`:    {"a,b", "c"},
+		`a\b,c\`:    {`a\b`, `c\`},
+		`x{1\,3},y`: {"x{1,3}", "y"},
+	} {
+		if got := splitList(s); !reflect.DeepEqual(got, want) {
+			t.Errorf("splitList(%q): got %q, want %q", s, got, want)
+		}
+	}
+}
+
+func TestListSet(t *testing.T) {
+	l := List[Duration]{Values: []Duration{{time.Hour}}}
+	checkOK(t, nil, !l.IsSet() && l.Len() == 1)
+	checkOK(t, l.Set("1s, 2s"), l.IsSet() && l.Len() == 2)
+	checkOK(t, l.Set("3s"), l.Len() == 3 && l.Values[2].Duration == 3*time.Second)
+	checkOK(t, nil, l.String() == "1s,2s,3s")
+	checkErr(t, l.Set("1s,x"))
+
+	var s List[string]
+	checkOK(t, s.Set(`a\,b,c`), reflect.DeepEqual(s.Values, []string{"a,b", "c"}))
+	checkOK(t, nil, s.String() == `a\,b,c`)
+	var s2 List[string]
+	checkOK(t, s2.Set(s.String()), reflect.DeepEqual(s2.Values, s.Values))
+}
+
+func TestListEscaped(t *testing.T) {
+	var r List[Regexp]
+	checkOK(t, r.Set(`a{1\,3},^b$`), r.Len() == 2 && r.Values[0].MatchString("aa"))
+	checkOK(t, nil, r.String() == `a{1\,3},^b$`)
+
+	var g List[Glob]
+	checkOK(t, json.Unmarshal([]byte(`"*.{go\\,mod}"`), &g), g.Len() == 1 && g.Values[0].Match("x.mod"))
+	checkOK(t, json.Unmarshal([]byte(`["*.{go,mod}"]`), &g), g.Len() == 1 && g.Values[0].Match("x.go"))
+
+	var u List[URL]
+	checkOK(t, u.Set("http://example.com/a,http://example.com/b"), u.Len() == 2)
+}
+
+func TestListMarshal(t *testing.T) {
+	checkJSON(t, List[Duration]{}, `[]`)
+	checkJSON(t, List[Duration]{Values: []Duration{{time.Second}, {time.Minute}}}, `["1s","1m0s"]`)
+	checkYAML(t, List[int]{Values: []int{1, 2}}, "- 1\n- 2")
+
+	var l List[int]
+	checkOK(t, json.Unmarshal([]byte(`"1,2,3"`), &l), reflect.DeepEqual(l.Values, []int{1, 2, 3}))
+	checkOK(t, yaml.Unmarshal([]byte(`4,5`), &l), reflect.DeepEqual(l.Values, []int{4, 5}))
+	checkOK(t, yaml.Unmarshal([]byte(`[6]`), &l), reflect.DeepEqual(l.Values, []int{6}) && l.IsSet())
+	checkErr(t, json.Unmarshal([]byte(`["x"]`), &l))
+	checkErr(t, yaml.Unmarshal([]byte(`{a: 1}`), &l))
+}
@@ -0,0 +1,271 @@
+/*
+ * Copyright 2018 Farsight Security, Inc.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package config
+
+import (
+	"crypto/tls"
+	"errors"
+	"net"
+	"os"
+	"strings"
+	"syscall"
+)
+
+type errAddrNotSet struct{}
+
+func (errAddrNotSet) Error() string {
+	return "Address not set"
+}
+
+// Listen creates a net.Listener on the Addr. Stale unix domain sockets
+// are removed as described for UnixAddr.Listen.
+//
+// For addresses in the "systemd" or "fd" networks, Listen returns a
+// listener on the inherited socket, e.g., "systemd:http" for the stream
+// socket passed by systemd with FileDescriptorName=http, or "fd:3".
+func (a Addr) Listen() (net.Listener, error) {
+	if a.Addr == nil {
+		return nil, errAddrNotSet{}
+	}
+	if isInheritedNetwork(a.Network()) {
+		return inheritedListener(a.Network(), a.String())
+	}
+	if isUnixNetwork(a.Network()) {
+		u := UnixAddr{UnixAddr: &net.UnixAddr{Net: a.Network(), Name: a.String()}}
+		return u.Listen()
+	}
+	return net.Listen(a.Network(), a.String())
+}
+
+// ListenTLS creates a net.Listener on the Addr which accepts TLS
+// connections configured by t. If t has not been loaded, ListenTLS
+// returns a plain listener as Listen does.
+func (a Addr) ListenTLS(t TLS) (net.Listener, error) {
+	return t.listener(a.Listen())
+}
+
+// ListenPacket creates a net.PacketConn on the Addr. For addresses in the
+// "systemd" or "fd" networks, ListenPacket returns a connection on the
+// inherited datagram socket.
+func (a Addr) ListenPacket() (net.PacketConn, error) {
+	if a.Addr == nil {
+		return nil, errAddrNotSet{}
+	}
+	if isInheritedNetwork(a.Network()) {
+		return inheritedPacketConn(a.Network(), a.String())
+	}
+	if isUnixNetwork(a.Network()) {
+		u := UnixAddr{UnixAddr: &net.UnixAddr{Net: a.Network(), Name: a.String()}}
+		return u.ListenPacket()
+	}
+	return net.ListenPacket(a.Network(), a.String())
+}
+
+// Listen creates a TCP listener on the TCPAddr.
+func (t TCPAddr) Listen() (net.Listener, error) {
+	if t.TCPAddr == nil {
+		return nil, errAddrNotSet{}
+	}
+	return net.ListenTCP("tcp", t.TCPAddr)
+}
+
+// ListenTLS creates a TCP listener on the TCPAddr which accepts TLS
+// connections configured by tc. If tc has not been loaded, ListenTLS
+// returns a plain listener as Listen does.
+func (t TCPAddr) ListenTLS(tc TLS) (net.Listener, error) {
+	return tc.listener(t.Listen())
+}
+
+// ListenPacket creates a UDP connection listening on the UDPAddr.
+func (u UDPAddr) ListenPacket() (net.PacketConn, error) {
+	if u.UDPAddr == nil {
+		return nil, errAddrNotSet{}
+	}
+	return net.ListenUDP("udp", u.UDPAddr)
+}
+
+// Listen creates a TCP listener on the first address of the TCPHost.
+func (t TCPHost) Listen() (net.Listener, error) {
+	a, err := t.Resolve()
+	if err != nil {
+		return nil, err
+	}
+	return net.ListenTCP(t.Network(), a)
+}
+
+// ListenTLS creates a TCP listener on the TCPHost which accepts TLS
+// connections configured by tc. If tc has not been loaded, ListenTLS
+// returns a plain listener as Listen does.
+func (t TCPHost) ListenTLS(tc TLS) (net.Listener, error) {
+	return tc.listener(t.Listen())
+}
+
+// ListenPacket creates a UDP connection listening on the first address of
+// the UDPHost.
+func (u UDPHost) ListenPacket() (net.PacketConn, error) {
+	a, err := u.Resolve()
+	if err != nil {
+		return nil, err
+	}
+	return net.ListenUDP(u.Network(), a)
+}
+
+// Listen creates a unix domain socket listener on the UnixAddr, which must
+// be in the "unix" or "unixpacket" network.
+//
+// If a socket file exists at the UnixAddr's path and connecting to it is
+// refused, Listen removes it before listening.
+func (u UnixAddr) Listen() (net.Listener, error) {
+	if u.UnixAddr == nil {
+		return nil, errAddrNotSet{}
+	}
+	removeStaleSocket(u.Net, u.Name)
+	return net.ListenUnix(u.Net, u.UnixAddr)
+}
+
+// ListenTLS creates a unix domain socket listener on the UnixAddr, as with
+// Listen, which accepts TLS connections configured by t. If t has not been
+// loaded, ListenTLS returns a plain listener.
+func (u UnixAddr) ListenTLS(t TLS) (net.Listener, error) {
+	return t.listener(u.Listen())
+}
+
+// ListenPacket creates a unix datagram socket on the UnixAddr, which must be
+// in the "unixgram" network. Stale sockets are removed as with Listen.
+func (u UnixAddr) ListenPacket() (net.PacketConn, error) {
+	if u.UnixAddr == nil {
+		return nil, errAddrNotSet{}
+	}
+	removeStaleSocket(u.Net, u.Name)
+	return net.ListenUnixgram(u.Net, u.UnixAddr)
+}
+
+// Listen creates a unix domain socket listener on the UnixSocket, as with
+// UnixAddr.Listen, and applies its Mode, Owner, and Group, if set.
+//
+// So that the socket is not accessible with the wrong permissions before
+// they are applied, the socket file is created with the process umask
+// temporarily set to 0077. The umask is shared by all goroutines, which
+// should not create files while the socket is being created.
+func (u UnixSocket) Listen() (net.Listener, error) {
+	if u.UnixAddr.UnixAddr == nil {
+		return nil, errAddrNotSet{}
+	}
+	if !u.hasOptions() {
+		return u.UnixAddr.Listen()
+	}
+	removeStaleSocket(u.Net, u.Name)
+	var l *net.UnixListener
+	var err error
+	oldMask := withUmask(0077, func() {
+		l, err = net.ListenUnix(u.Net, u.UnixAddr.UnixAddr)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err = u.applyOptions(oldMask); err != nil {
+		l.Close()
+		return nil, err
+	}
+	return l, nil
+}
+
+// ListenTLS creates a unix domain socket listener on the UnixSocket, as
+// with Listen, which accepts TLS connections configured by t. If t has not
+// been loaded, ListenTLS returns a plain listener.
+func (u UnixSocket) ListenTLS(t TLS) (net.Listener, error) {
+	return t.listener(u.Listen())
+}
+
+// ListenPacket creates a unix datagram socket on the UnixSocket, as with
+// UnixAddr.ListenPacket, and applies its Mode, Owner and Group as with
+// Listen.
+func (u UnixSocket) ListenPacket() (net.PacketConn, error) {
+	if u.UnixAddr.UnixAddr == nil {
+		return nil, errAddrNotSet{}
+	}
+	if !u.hasOptions() {
+		return u.UnixAddr.ListenPacket()
+	}
+	removeStaleSocket(u.Net, u.Name)
+	var c *net.UnixConn
+	var err error
+	oldMask := withUmask(0077, func() {
+		c, err = net.ListenUnixgram(u.Net, u.UnixAddr.UnixAddr)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err = u.applyOptions(oldMask); err != nil {
+		c.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+func (t TLS) listener(l net.Listener, err error) (net.Listener, error) {
+	if err != nil || t.Config == nil {
+		return l, err
+	}
+	return tls.NewListener(l, t.Config), nil
+}
+
+func isUnixNetwork(n string) bool {
+	return n == "unix" || n == "unixpacket" || n == "unixgram"
+}
+
+func isAbstractSocket(path string) bool {
+	return strings.HasPrefix(path, "@")
+}
+
+// removeStaleSocket removes the socket file at path if connecting to it
+// is refused, i.e., no process is listening on it.
+func removeStaleSocket(network, path string) {
+	if isAbstractSocket(path) {
+		return
+	}
+	fi, err := os.Lstat(path)
+	if err != nil || fi.Mode()&os.ModeSocket == 0 {
+		return
+	}
+	c, err := net.Dial(network, path)
+	if err == nil {
+		c.Close()
+		return
+	}
+	if errors.Is(err, syscall.ECONNREFUSED) {
+		os.Remove(path)
+	}
+}
+
+// applyOptions sets the owner, group and mode of the socket file, which
+// was created with a umask of 0077. If no Mode is set, the permissions
+// the file would have had with the process umask oldMask are restored.
+func (u UnixSocket) applyOptions(oldMask int) error {
+	if isAbstractSocket(u.Name) {
+		return nil
+	}
+	if u.Owner.IsSet() || u.Group.IsSet() {
+		uid, gid := -1, -1
+		if u.Owner.IsSet() {
+			uid = u.Owner.UID
+		}
+		if u.Group.IsSet() {
+			gid = u.Group.GID
+		}
+		if err := os.Chown(u.Name, uid, gid); err != nil {
+			return err
+		}
+	}
+	mode := os.FileMode(0777 &^ oldMask)
+	if u.Mode.IsSet() {
+		mode = u.Mode.FileMode
+	}
+	return os.Chmod(u.Name, mode)
+}
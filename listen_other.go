@@ -0,0 +1,18 @@
+/*
+ * Copyright 2018 Farsight Security, Inc.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+//go:build !unix
+
+package config
+
+// withUmask calls f. Systems other than unix have no umask, so it returns
+// zero.
+func withUmask(mask int, f func()) int {
+	f()
+	return 0
+}
@@ -0,0 +1,105 @@
+/*
+ * Copyright 2018 Farsight Security, Inc.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package config
+
+import (
+	"encoding/json"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+func TestUnixAddr(t *testing.T) {
+	var u UnixAddr
+	checkOK(t, nil, !u.IsSet())
+	checkOK(t, u.Set("/run/app.sock"), u.IsSet() && u.Net == "unix" && u.Name == "/run/app.sock")
+	checkOK(t, u.Set("unixgram:/run/app.sock"), u.Net == "unixgram")
+	checkErr(t, u.Set("tcp:localhost:80"))
+
+	checkOK(t, nil, UnixAddr{&net.UnixAddr{Net: "unix", Name: "/x"}}.IsSet())
+	checkJSON(t, UnixAddr{&net.UnixAddr{Net: "unix", Name: "/x"}}, `"unix:/x"`)
+	checkYAML(t, UnixAddr{&net.UnixAddr{Net: "unixgram", Name: "/x"}}, `unixgram:/x`)
+}
+
+func TestUnixSocketConfig(t *testing.T) {
+	var u UnixSocket
+	checkOK(t, json.Unmarshal([]byte(`"unix:/run/a.sock"`), &u), u.IsSet() && !u.hasOptions())
+	checkOK(t, yaml.Unmarshal([]byte("address: /run/a.sock\nmode: rw-rw----\nowner: 0"), &u),
+		u.Name == "/run/a.sock" && u.Mode.FileMode == 0660 && u.Owner.UID == 0 && !u.Group.IsSet())
+	checkErr(t, json.Unmarshal([]byte(`{"address":"/x","mode":"999"}`), &u))
+	checkErr(t, json.Unmarshal([]byte(`{"address":"tcp:x:1"}`), &u))
+
+	s := UnixSocket{UnixAddr: UnixAddr{&net.UnixAddr{Net: "unix", Name: "/x"}}}
+	checkJSON(t, s, `"unix:/x"`)
+	s.Mode.Set("0600")
+	checkJSON(t, s, `{"address":"unix:/x","mode":"0600"}`)
+	checkYAML(t, s, "address: unix:/x\nmode: \"0600\"")
+}
+
+func TestUnixListen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "s")
+	var u UnixAddr
+	checkOK(t, u.Set(path), true)
+
+	// A socket file left behind by a process which has exited is removed.
+	l, err := u.Listen()
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.(*net.UnixListener).SetUnlinkOnClose(false)
+	l.Close()
+	l, err = u.Listen()
+	checkOK(t, err, l != nil)
+
+	// An active socket is left alone.
+	_, err = u.Listen()
+	checkErr(t, err)
+	l.Close()
+
+	// So is a file which is not a socket.
+	checkOK(t, os.WriteFile(path, nil, 0644), true)
+	_, err = u.Listen()
+	checkErr(t, err)
+	_, err = os.Stat(path)
+	checkOK(t, err, true)
+
+	_, err = UnixAddr{}.Listen()
+	checkErr(t, err)
+}
+
+func TestUnixSocketListen(t *testing.T) {
+	dir := t.TempDir()
+	var u UnixSocket
+	checkOK(t, u.UnixAddr.Set(filepath.Join(dir, "s")), true)
+	checkOK(t, u.Mode.Set("rw-r-----"), true)
+	checkOK(t, u.Owner.Set(strconv.Itoa(os.Getuid())), true)
+	checkOK(t, u.Group.Set(strconv.Itoa(os.Getgid())), true)
+
+	l, err := u.Listen()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	fi, err := os.Stat(u.Name)
+	checkOK(t, err, fi.Mode().Perm() == 0640)
+
+	g := UnixSocket{Mode: u.Mode}
+	checkOK(t, g.UnixAddr.Set("unixgram:"+filepath.Join(dir, "g")), true)
+	c, err := g.ListenPacket()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	fi, err = os.Stat(g.Name)
+	checkOK(t, err, fi.Mode().Perm() == 0640)
+}
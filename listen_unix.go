@@ -0,0 +1,29 @@
+/*
+ * Copyright 2018 Farsight Security, Inc.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+//go:build unix
+
+package config
+
+import (
+	"sync"
+	"syscall"
+)
+
+var umaskMu sync.Mutex
+
+// withUmask calls f with the process umask set to mask, returning the
+// previous umask.
+func withUmask(mask int, f func()) int {
+	umaskMu.Lock()
+	defer umaskMu.Unlock()
+	old := syscall.Umask(mask)
+	defer syscall.Umask(old)
+	f()
+	return old
+}
@@ -0,0 +1,198 @@
+/*
+ * Copyright 2018 Farsight Security, Inc.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// ListenerSet is a list of addresses for a service to listen on, e.g.:
+//
+//      listen: ["tcp:0.0.0.0:8080", "tcp:[::]:8080", "unix:/run/app/admin.sock"]
+//
+// Each address is an Addr, and may be in any form Addr accepts, including
+// inherited sockets. As with List, a ListenerSet may be given in JSON or
+// YAML as a sequence or a single comma-separated string, and as a flag
+// with comma-separated or repeated values.
+//
+// Listen opens all of the addresses at once, Serve runs a server on all of
+// them, and Close closes them together.
+type ListenerSet struct {
+	List[Addr]
+	mu        sync.Mutex
+	listeners []net.Listener
+}
+
+// ListenError records the failure of an operation on one address of a
+// ListenerSet.
+type ListenError struct {
+	Addr string
+	Err  error
+}
+
+func (e ListenError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Addr, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e ListenError) Unwrap() error {
+	return e.Err
+}
+
+// ListenErrors lists the failures of an operation on a ListenerSet, one
+// for each address which failed.
+type ListenErrors []ListenError
+
+func (l ListenErrors) Error() string {
+	s := make([]string, len(l))
+	for i, e := range l {
+		s[i] = e.Error()
+	}
+	return strings.Join(s, "; ")
+}
+
+func (l ListenErrors) err() error {
+	if len(l) == 0 {
+		return nil
+	}
+	return l
+}
+
+// Listen opens a listener on each address in the ListenerSet. If any of
+// the addresses fail, Listen closes the listeners it opened and returns a
+// ListenErrors listing the failures. Listen returns an error if the
+// ListenerSet's listeners are already open; Close them to listen again.
+func (ls *ListenerSet) Listen() error {
+	return ls.listen(func(a Addr) (net.Listener, error) {
+		return a.Listen()
+	})
+}
+
+// ListenTLS opens a listener on each address in the ListenerSet as with
+// Listen, accepting TLS connections configured by t.
+func (ls *ListenerSet) ListenTLS(t TLS) error {
+	return ls.listen(func(a Addr) (net.Listener, error) {
+		return a.ListenTLS(t)
+	})
+}
+
+type errListening struct{}
+
+func (errListening) Error() string {
+	return "ListenerSet is already listening"
+}
+
+func (ls *ListenerSet) listening() bool {
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+	return ls.listeners != nil
+}
+
+func (ls *ListenerSet) listen(listen func(Addr) (net.Listener, error)) error {
+	if ls.listening() {
+		return errListening{}
+	}
+	var errs ListenErrors
+	listeners := make([]net.Listener, 0, len(ls.Values))
+	for _, a := range ls.Values {
+		l, err := listen(a)
+		if err != nil {
+			errs = append(errs, ListenError{addrString(a), err})
+			continue
+		}
+		listeners = append(listeners, l)
+	}
+	if len(errs) > 0 {
+		for _, l := range listeners {
+			l.Close()
+		}
+		return errs
+	}
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+	if ls.listeners != nil {
+		for _, l := range listeners {
+			l.Close()
+		}
+		return errListening{}
+	}
+	ls.listeners = listeners
+	return nil
+}
+
+// Listeners returns the listeners opened by Listen, in the order of the
+// ListenerSet's addresses.
+func (ls *ListenerSet) Listeners() []net.Listener {
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+	return append([]net.Listener(nil), ls.listeners...)
+}
+
+// Serve calls serve on each of the ListenerSet's listeners concurrently,
+// opening them first if Listen has not been called. It returns when all of
+// the calls have returned, with a ListenErrors listing any errors other
+// than those caused by Close.
+func (ls *ListenerSet) Serve(serve func(net.Listener) error) error {
+	listeners := ls.Listeners()
+	if listeners == nil {
+		if err := ls.Listen(); err != nil {
+			return err
+		}
+		listeners = ls.Listeners()
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	var errs ListenErrors
+	for i, l := range listeners {
+		wg.Add(1)
+		go func(a string, l net.Listener) {
+			defer wg.Done()
+			err := serve(l)
+			if err == nil || errors.Is(err, net.ErrClosed) ||
+				errors.Is(err, http.ErrServerClosed) {
+				return
+			}
+			mu.Lock()
+			errs = append(errs, ListenError{a, err})
+			mu.Unlock()
+		}(addrString(ls.Values[i]), l)
+	}
+	wg.Wait()
+	return errs.err()
+}
+
+// ServeHandler serves HTTP requests with handler h on all of the
+// ListenerSet's listeners, as with Serve.
+func (ls *ListenerSet) ServeHandler(h http.Handler) error {
+	srv := &http.Server{Handler: h}
+	return ls.Serve(srv.Serve)
+}
+
+// Close closes all of the ListenerSet's listeners, returning a
+// ListenErrors listing any which failed.
+func (ls *ListenerSet) Close() error {
+	ls.mu.Lock()
+	listeners := ls.listeners
+	ls.listeners = nil
+	ls.mu.Unlock()
+
+	var errs ListenErrors
+	for i, l := range listeners {
+		if err := l.Close(); err != nil && !errors.Is(err, net.ErrClosed) {
+			errs = append(errs, ListenError{addrString(ls.Values[i]), err})
+		}
+	}
+	return errs.err()
+}
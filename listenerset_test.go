@@ -0,0 +1,56 @@
+/*
+ * Copyright 2018 Farsight Security, Inc.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package config
+
+import (
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestListenerSet(t *testing.T) {
+	var ls ListenerSet
+	checkOK(t, ls.Set("tcp:127.0.0.1:0,tcp:127.0.0.1:0"), len(ls.Values) == 2)
+	checkOK(t, ls.Listen(), len(ls.Listeners()) == 2)
+	checkErr(t, ls.Listen())
+	checkOK(t, nil, len(ls.Listeners()) == 2)
+
+	l := ls.Listeners()[0]
+	checkOK(t, ls.Close(), ls.Listeners() == nil)
+	_, err := l.Accept()
+	checkOK(t, nil, errors.Is(err, net.ErrClosed))
+
+	checkOK(t, ls.Listen(), len(ls.Listeners()) == 2)
+	checkOK(t, ls.Close(), true)
+}
+
+func TestListenerSetPartialFailure(t *testing.T) {
+	busy, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer busy.Close()
+
+	path := filepath.Join(t.TempDir(), "test.sock")
+	var ls ListenerSet
+	checkOK(t, ls.Set("unix:"+path+",tcp:"+busy.Addr().String()), true)
+
+	err = ls.Listen()
+	var errs ListenErrors
+	checkOK(t, nil, errors.As(err, &errs) && len(errs) == 1 &&
+		errs[0].Addr == "tcp:"+busy.Addr().String())
+	checkOK(t, nil, ls.Listeners() == nil)
+
+	// The unix listener which was opened has been closed, removing its
+	// socket file.
+	_, err = os.Stat(path)
+	checkOK(t, nil, os.IsNotExist(err))
+}
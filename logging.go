@@ -0,0 +1,484 @@
+/*
+ * Copyright 2018 Farsight Security, Inc.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Logging contains the logging configuration for a program as it appears
+// in a JSON or YAML config, e.g.:
+//
+//      logging:
+//        level: debug
+//        format: json
+//        output: /var/log/app.log
+//        maxSize: 100MB
+//        maxBackups: 3
+//
+// The Level, Format and Output fields are also flag.Values, and may be
+// registered as command line flags together with RegisterFlags.
+//
+// Handler builds a log/slog handler from the configuration. The handler's
+// level is shared with the Logging, so it may be changed while the program
+// runs with SetLevel, or with Reload when the configuration is reloaded.
+// Handler, Reload and Close should not be called concurrently.
+type Logging struct {
+	Level      LogLevel  `json:"level,omitempty" yaml:"level,omitempty"`
+	Format     LogFormat `json:"format,omitempty" yaml:"format,omitempty"`
+	Output     LogOutput `json:"output,omitempty" yaml:"output,omitempty"`
+	MaxSize    ByteSize  `json:"maxSize,omitempty" yaml:"maxSize,omitempty"`
+	MaxBackups int       `json:"maxBackups,omitempty" yaml:"maxBackups,omitempty"`
+	AddSource  bool      `json:"addSource,omitempty" yaml:"addSource,omitempty"`
+
+	levelVar *slog.LevelVar
+	w        io.WriteCloser
+}
+
+// RegisterFlags registers the Level, Format and Output of the Logging as
+// command line flags in fs, named with the given prefix, e.g., with prefix
+// "log-", as -log-level, -log-format and -log-output.
+func (l *Logging) RegisterFlags(fs *flag.FlagSet, prefix string) {
+	fs.Var(&l.Level, prefix+"level", "log level (debug, info, warn, error)")
+	fs.Var(&l.Format, prefix+"format", "log format (text, json)")
+	fs.Var(&l.Output, prefix+"output", "log output (stderr, stdout, syslog[:addr], or file path)")
+}
+
+// Leveler returns the slog.LevelVar used by the Logging's handlers.
+func (l *Logging) Leveler() *slog.LevelVar {
+	if l.levelVar == nil {
+		l.levelVar = new(slog.LevelVar)
+		l.levelVar.Set(l.Level.Level)
+	}
+	return l.levelVar
+}
+
+// SetLevel changes the level of the Logging and of the handlers built
+// from it.
+func (l *Logging) SetLevel(level slog.Level) {
+	l.Level = LogLevel{Level: level, set: true}
+	l.Leveler().Set(level)
+}
+
+// Reload applies the level of a newly loaded configuration n to the
+// handlers built from l, and makes n share them, so that a later Reload
+// from n also affects them. Other settings take effect only in handlers
+// built from n.
+func (l *Logging) Reload(n *Logging) {
+	lv := l.Leveler()
+	lv.Set(n.Level.Level)
+	n.levelVar = lv
+}
+
+// Handler returns a slog.Handler writing to the configured Output in the
+// configured Format. Files are opened for appending, and rotated when
+// MaxSize is set, keeping MaxBackups (by default, one) old files named with
+// the suffixes ".1", ".2", etc.
+//
+// The output is opened once, and shared by later calls to Handler until
+// Close is called.
+func (l *Logging) Handler() (slog.Handler, error) {
+	if l.w == nil {
+		w, err := l.open()
+		if err != nil {
+			return nil, err
+		}
+		l.w = w
+	}
+	opts := &slog.HandlerOptions{
+		Level:     l.Leveler(),
+		AddSource: l.AddSource,
+	}
+	sw, isSyslog := l.w.(*syslogWriter)
+	if isSyslog {
+		// syslog records its own timestamp.
+		opts.ReplaceAttr = func(groups []string, a slog.Attr) slog.Attr {
+			if len(groups) == 0 && a.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return a
+		}
+	}
+	var h slog.Handler
+	if l.Format.Value == logFormatJSON {
+		h = slog.NewJSONHandler(l.w, opts)
+	} else {
+		h = slog.NewTextHandler(l.w, opts)
+	}
+	if isSyslog {
+		h = &syslogHandler{Handler: h, w: sw}
+	}
+	return h, nil
+}
+
+// Logger returns a slog.Logger using the Logging's Handler.
+func (l *Logging) Logger() (*slog.Logger, error) {
+	h, err := l.Handler()
+	if err != nil {
+		return nil, err
+	}
+	return slog.New(h), nil
+}
+
+// Close closes the output opened by Handler, if it is a file or syslog
+// connection.
+func (l *Logging) Close() error {
+	w := l.w
+	l.w = nil
+	if w == nil {
+		return nil
+	}
+	return w.Close()
+}
+
+func (l *Logging) open() (io.WriteCloser, error) {
+	switch l.Output.kind {
+	case logOutputStdout:
+		return nopCloser{os.Stdout}, nil
+	case logOutputSyslog:
+		return newSyslogWriter(l.Output.syslog)
+	case logOutputFile:
+		backups := l.MaxBackups
+		if backups <= 0 {
+			backups = 1
+		}
+		return openLogFile(l.Output.path, l.MaxSize.Bytes, backups)
+	}
+	return nopCloser{os.Stderr}, nil
+}
+
+type nopCloser struct{ io.Writer }
+
+func (nopCloser) Close() error { return nil }
+
+// LogLevel is a slog.Level which may be given by name: "debug", "info",
+// "warn" (or "warning"), or "error", case insensitive, optionally with an
+// offset, e.g., "debug-2" or "error+4". The default level is "info".
+//
+// The names are registered as an Enum of slog.Level, so Enum[slog.Level]
+// accepts them too. LogLevel is not itself an Enum, as the offsets give
+// levels with no registered name.
+type LogLevel struct {
+	slog.Level
+	set bool
+}
+
+func init() {
+	if err := RegisterEnum(
+		EnumName[slog.Level]{"debug", slog.LevelDebug},
+		EnumName[slog.Level]{"info", slog.LevelInfo},
+		EnumName[slog.Level]{"warn", slog.LevelWarn},
+		EnumName[slog.Level]{"warning", slog.LevelWarn},
+		EnumName[slog.Level]{"error", slog.LevelError},
+	); err != nil {
+		panic(err)
+	}
+}
+
+// Set satisfies the flag.Value interface.
+func (l *LogLevel) Set(s string) error {
+	name, offset := strings.TrimSpace(s), ""
+	if i := strings.IndexAny(name, "+-"); i >= 0 {
+		name, offset = name[:i], name[i:]
+	}
+	var e Enum[slog.Level]
+	if err := e.Set(name); err != nil {
+		return err
+	}
+	level := e.Value
+	if offset != "" {
+		n, err := strconv.Atoi(offset)
+		if err != nil {
+			return errLogLevelOffset(s)
+		}
+		level += slog.Level(n)
+	}
+	l.Level = level
+	l.set = true
+	return nil
+}
+
+type errLogLevelOffset string
+
+func (e errLogLevelOffset) Error() string {
+	return fmt.Sprintf("Invalid log level offset in '%s'", string(e))
+}
+
+// String satisfies the flag.Value interface.
+func (l LogLevel) String() string {
+	return strings.ToLower(l.Level.String())
+}
+
+// IsSet returns true if the LogLevel was assigned a value with Set or by
+// unmarshaling.
+func (l LogLevel) IsSet() bool {
+	return l.set
+}
+
+// MarshalJSON satisfies the json.Marshaler interface
+func (l LogLevel) MarshalJSON() ([]byte, error) {
+	return json.Marshal(l.String())
+}
+
+// MarshalYAML satisfies the yaml.Marshaler interface
+func (l LogLevel) MarshalYAML() (interface{}, error) {
+	return l.String(), nil
+}
+
+// UnmarshalJSON satisfies the json.Unmarshaler interface
+func (l *LogLevel) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	return l.Set(s)
+}
+
+// UnmarshalYAML satisfies the yaml.Unmarshaler interface
+func (l *LogLevel) UnmarshalYAML(u func(interface{}) error) error {
+	var s string
+	if err := u(&s); err != nil {
+		return err
+	}
+	return l.Set(s)
+}
+
+// LogFormat selects the output format of a Logging handler: "text" (the
+// default) for slog.TextHandler, or "json" for slog.JSONHandler.
+type LogFormat struct{ Enum[logFormat] }
+
+type logFormat int
+
+const (
+	logFormatText logFormat = iota
+	logFormatJSON
+)
+
+func init() {
+	if err := RegisterEnum(
+		EnumName[logFormat]{"text", logFormatText},
+		EnumName[logFormat]{"json", logFormatJSON},
+	); err != nil {
+		panic(err)
+	}
+}
+
+type logOutputKind int
+
+const (
+	logOutputStderr logOutputKind = iota
+	logOutputStdout
+	logOutputSyslog
+	logOutputFile
+)
+
+// LogOutput is the destination of a Logging handler: "stderr" (the
+// default), "stdout", "syslog" for the local syslog daemon, "syslog:addr"
+// for a syslog server at the Addr addr, e.g., "syslog:udp:loghost:514" or
+// "syslog:loghost" (the network defaults to "udp", and the port to 514),
+// or otherwise the path of a file.
+type LogOutput struct {
+	kind   logOutputKind
+	path   string
+	syslog Addr
+}
+
+// Set satisfies the flag.Value interface.
+func (o *LogOutput) Set(s string) error {
+	var n LogOutput
+	switch {
+	case s == "" || s == "stderr":
+		n.kind = logOutputStderr
+	case s == "stdout":
+		n.kind = logOutputStdout
+	case s == "syslog":
+		n.kind = logOutputSyslog
+	case strings.HasPrefix(s, "syslog:"):
+		n.kind = logOutputSyslog
+		a, err := parseAddr(s[len("syslog:"):], "udp", "514")
+		if err != nil {
+			return err
+		}
+		n.syslog = Addr{a}
+	default:
+		n.kind = logOutputFile
+		n.path = s
+	}
+	*o = n
+	return nil
+}
+
+// String satisfies the flag.Value interface.
+func (o LogOutput) String() string {
+	switch o.kind {
+	case logOutputStdout:
+		return "stdout"
+	case logOutputSyslog:
+		if o.syslog.Addr != nil {
+			return "syslog:" + addrString(o.syslog)
+		}
+		return "syslog"
+	case logOutputFile:
+		return o.path
+	}
+	return "stderr"
+}
+
+// Path returns the path of the LogOutput if it is a file, or the empty
+// string otherwise.
+func (o LogOutput) Path() string {
+	return o.path
+}
+
+// MarshalJSON satisfies the json.Marshaler interface
+func (o LogOutput) MarshalJSON() ([]byte, error) {
+	return json.Marshal(o.String())
+}
+
+// MarshalYAML satisfies the yaml.Marshaler interface
+func (o LogOutput) MarshalYAML() (interface{}, error) {
+	return o.String(), nil
+}
+
+// UnmarshalJSON satisfies the json.Unmarshaler interface
+func (o *LogOutput) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	return o.Set(s)
+}
+
+// UnmarshalYAML satisfies the yaml.Unmarshaler interface
+func (o *LogOutput) UnmarshalYAML(u func(interface{}) error) error {
+	var s string
+	if err := u(&s); err != nil {
+		return err
+	}
+	return o.Set(s)
+}
+
+// logFile is a log file which is rotated when it reaches maxSize bytes.
+type logFile struct {
+	mu      sync.Mutex
+	path    string
+	maxSize uint64
+	backups int
+	f       *os.File
+	size    uint64
+}
+
+func openLogFile(path string, maxSize uint64, backups int) (*logFile, error) {
+	lf := &logFile{path: path, maxSize: maxSize, backups: backups}
+	if err := lf.open(); err != nil {
+		return nil, err
+	}
+	return lf, nil
+}
+
+func (lf *logFile) open() error {
+	f, err := os.OpenFile(lf.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	lf.f, lf.size = f, uint64(fi.Size())
+	return nil
+}
+
+// Write writes p to the log file, rotating it first if p would take it
+// past maxSize. If rotation fails, p is still written to the reopened file
+// and the rotation error is returned.
+func (lf *logFile) Write(p []byte) (int, error) {
+	lf.mu.Lock()
+	defer lf.mu.Unlock()
+	var rerr error
+	if lf.maxSize > 0 && lf.size > 0 && lf.size+uint64(len(p)) > lf.maxSize {
+		if rerr = lf.rotate(); rerr != nil && lf.f != nil {
+			// The file was reopened but not renamed. Rotation is not
+			// tried again until another maxSize bytes are written.
+			lf.size = 0
+		}
+	}
+	if lf.f == nil {
+		// A previous rotation failed to reopen the file.
+		if err := lf.open(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := lf.f.Write(p)
+	lf.size += uint64(n)
+	if err == nil {
+		err = rerr
+	}
+	return n, err
+}
+
+// rotate renames the log file and its backups and opens a new file. The
+// file at the original path is reopened whether or not the renames
+// succeed, so that logging continues; if it cannot be, lf.f is left nil.
+func (lf *logFile) rotate() error {
+	err := lf.f.Close()
+	lf.f = nil
+	if err == nil {
+		for i := lf.backups - 1; i > 0; i-- {
+			os.Rename(fmt.Sprintf("%s.%d", lf.path, i),
+				fmt.Sprintf("%s.%d", lf.path, i+1))
+		}
+		err = os.Rename(lf.path, lf.path+".1")
+	}
+	if oerr := lf.open(); oerr != nil {
+		return oerr
+	}
+	return err
+}
+
+func (lf *logFile) Close() error {
+	lf.mu.Lock()
+	defer lf.mu.Unlock()
+	if lf.f == nil {
+		return nil
+	}
+	return lf.f.Close()
+}
+
+// syslogHandler passes the level of each record to the syslogWriter, so
+// that it is sent with the corresponding severity.
+type syslogHandler struct {
+	slog.Handler
+	w *syslogWriter
+}
+
+func (h *syslogHandler) Handle(ctx context.Context, r slog.Record) error {
+	h.w.handleMu.Lock()
+	defer h.w.handleMu.Unlock()
+	h.w.severity = syslogSeverity(r.Level)
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h *syslogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &syslogHandler{Handler: h.Handler.WithAttrs(attrs), w: h.w}
+}
+
+func (h *syslogHandler) WithGroup(name string) slog.Handler {
+	return &syslogHandler{Handler: h.Handler.WithGroup(name), w: h.w}
+}
@@ -0,0 +1,108 @@
+/*
+ * Copyright 2018 Farsight Security, Inc.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package config
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+func TestLogLevel(t *testing.T) {
+	var l LogLevel
+	checkOK(t, nil, !l.IsSet() && l.String() == "info")
+	checkOK(t, l.Set("DEBUG"), l.Level == slog.LevelDebug && l.IsSet())
+	checkOK(t, l.Set("warning"), l.Level == slog.LevelWarn && l.String() == "warn")
+	checkOK(t, l.Set("error+4"), l.Level == slog.LevelError+4 && l.String() == "error+4")
+	checkOK(t, l.Set("debug-2"), l.Level == slog.LevelDebug-2)
+	checkJSON(t, LogLevel{Level: slog.LevelWarn}, `"warn"`)
+	checkYAML(t, LogLevel{Level: slog.LevelInfo + 2}, `info+2`)
+
+	for _, s := range []string{"", "verbose", "info+", "info+x", "+2"} {
+		checkErr(t, l.Set(s))
+	}
+
+	var e Enum[slog.Level]
+	checkOK(t, e.Set("Warning"), e.Value == slog.LevelWarn && e.String() == "warn")
+}
+
+func TestLoggingConfig(t *testing.T) {
+	var l Logging
+	checkOK(t, yaml.Unmarshal([]byte(`
+level: debug
+format: json
+output: syslog:192.0.2.1
+`), &l), l.Level.Level == slog.LevelDebug && l.Format.Value == logFormatJSON &&
+		l.Output.String() == "syslog:udp:192.0.2.1:514")
+	checkErr(t, yaml.Unmarshal([]byte(`format: xml`), &l))
+	checkErr(t, yaml.Unmarshal([]byte(`output: "syslog:[::1"`), &l))
+
+	var n Logging
+	checkOK(t, n.Level.Set("error"), true)
+	lv := l.Leveler()
+	l.Reload(&n)
+	checkOK(t, nil, lv.Level() == slog.LevelError && n.Leveler() == lv)
+	n.SetLevel(slog.LevelWarn)
+	checkOK(t, nil, lv.Level() == slog.LevelWarn)
+}
+
+func TestLogFileRotate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	l := Logging{MaxBackups: 2}
+	checkOK(t, l.Output.Set(path), l.Output.Path() == path)
+	checkOK(t, l.MaxSize.Set("100B"), true)
+	logger, err := l.Logger()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	for i := 0; i < 10; i++ {
+		logger.Info("message", "n", i)
+	}
+	for _, p := range []string{path, path + ".1", path + ".2"} {
+		_, err := os.Stat(p)
+		checkOK(t, err, true)
+	}
+	_, err = os.Stat(path + ".3")
+	checkOK(t, nil, os.IsNotExist(err))
+
+	// If the file cannot be renamed, logging continues in the original
+	// file.
+	os.Remove(path + ".1")
+	checkOK(t, os.MkdirAll(filepath.Join(path+".1", "x"), 0755), true)
+	logger.Info("after failed rotation")
+	b, err := os.ReadFile(path)
+	checkOK(t, err, strings.Contains(string(b), "after failed rotation"))
+}
+
+func TestLogFileRotateError(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	lf, err := openLogFile(path, 10, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lf.Close()
+	checkOK(t, os.MkdirAll(filepath.Join(path+".1", "x"), 0755), true)
+
+	_, err = lf.Write([]byte("0123456789"))
+	checkOK(t, err, true)
+	// The failed rotation is reported, and the line is still written.
+	n, err := lf.Write([]byte("abc"))
+	checkOK(t, nil, err != nil && n == 3)
+	// Rotation is not retried on the next write.
+	_, err = lf.Write([]byte("def"))
+	checkOK(t, err, true)
+	b, err := os.ReadFile(path)
+	checkOK(t, err, string(b) == "0123456789abcdef")
+}
@@ -0,0 +1,171 @@
+/*
+ * Copyright 2018 Farsight Security, Inc.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Map is a mapping from keys of type K to values of type V, each of which
+// may be any of the types in this package or a primitive type. In JSON and
+// YAML, a Map is a mapping from the key type's string representation to the
+// value type's representation.
+//
+// As a flag.Value, a Map accepts comma-separated name=value pairs, repeated
+// flags, or both:
+//
+//      -backend api=https://api.example.com/ -backend www=https://example.com/
+//
+// As with List, the first call to Set replaces any existing values and
+// subsequent calls add to them. Keys and values are parsed by their types'
+// Set methods. A comma within a name or value is written as "\,".
+//
+// Elem, if set before Set or unmarshaling, is the starting value of each
+// value in the Map, as described for List.
+type Map[K comparable, V any] struct {
+	Values    map[K]V
+	Elem      V
+	set, flag bool
+}
+
+type errMapFormatInvalid string
+
+func (e errMapFormatInvalid) Error() string {
+	return fmt.Sprintf("Invalid map entry '%s': should be name=value",
+		string(e))
+}
+
+func (m *Map[K, V]) elems() reflect.Value {
+	return reflect.ValueOf(m.Values)
+}
+
+// IsSet returns true if the Map was assigned a value with Set or by
+// unmarshaling.
+func (m Map[K, V]) IsSet() bool {
+	return m.set
+}
+
+// Len returns the number of entries in the Map.
+func (m Map[K, V]) Len() int {
+	return len(m.Values)
+}
+
+// String satisfies the flag.Value interface, returning the Map's entries
+// as comma-separated name=value pairs sorted by name.
+func (m *Map[K, V]) String() string {
+	if m == nil {
+		return ""
+	}
+	var s []string
+	for k, v := range m.Values {
+		s = append(s, escapeList(formatValue(&k))+"="+escapeList(formatValue(&v)))
+	}
+	sort.Strings(s)
+	return strings.Join(s, ",")
+}
+
+// Set satisfies the flag.Value interface.
+func (m *Map[K, V]) Set(s string) error {
+	vals := make(map[K]V)
+	for _, e := range splitList(strings.TrimSpace(s)) {
+		kv := strings.SplitN(e, "=", 2)
+		if len(kv) < 2 {
+			return errMapFormatInvalid(e)
+		}
+		var k K
+		v := m.Elem
+		if err := setValue(&k, strings.TrimSpace(kv[0])); err != nil {
+			return err
+		}
+		if err := setValue(&v, strings.TrimSpace(kv[1])); err != nil {
+			return err
+		}
+		vals[k] = v
+	}
+	if !m.flag || m.Values == nil {
+		m.Values = vals
+	} else {
+		for k, v := range vals {
+			m.Values[k] = v
+		}
+	}
+	m.set, m.flag = true, true
+	return nil
+}
+
+func (m *Map[K, V]) unmarshaled(raw map[string]V) error {
+	vals := make(map[K]V, len(raw))
+	for ks, v := range raw {
+		var k K
+		if err := setValue(&k, ks); err != nil {
+			return err
+		}
+		vals[k] = v
+	}
+	m.Values = vals
+	m.set, m.flag = true, false
+	return nil
+}
+
+func (m Map[K, V]) marshaled() map[string]*V {
+	raw := make(map[string]*V, len(m.Values))
+	for k, v := range m.Values {
+		v := v
+		raw[formatValue(&k)] = &v
+	}
+	return raw
+}
+
+// MarshalJSON satisfies the json.Marshaler interface
+func (m Map[K, V]) MarshalJSON() ([]byte, error) {
+	return json.Marshal(m.marshaled())
+}
+
+// MarshalYAML satisfies the yaml.Marshaler interface
+func (m Map[K, V]) MarshalYAML() (interface{}, error) {
+	return m.marshaled(), nil
+}
+
+// UnmarshalJSON satisfies the json.Unmarshaler interface
+func (m *Map[K, V]) UnmarshalJSON(b []byte) error {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+	vals := make(map[string]V, len(raw))
+	for k, r := range raw {
+		v := m.Elem
+		if err := json.Unmarshal(r, &v); err != nil {
+			return err
+		}
+		vals[k] = v
+	}
+	return m.unmarshaled(vals)
+}
+
+// UnmarshalYAML satisfies the yaml.Unmarshaler interface
+func (m *Map[K, V]) UnmarshalYAML(u func(interface{}) error) error {
+	var raw map[string]yamlElem
+	if err := u(&raw); err != nil {
+		return err
+	}
+	vals := make(map[string]V, len(raw))
+	for k, e := range raw {
+		v := m.Elem
+		if err := e.unmarshal(&v); err != nil {
+			return err
+		}
+		vals[k] = v
+	}
+	return m.unmarshaled(vals)
+}
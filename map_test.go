@@ -0,0 +1,41 @@
+/*
+ * Copyright 2018 Farsight Security, Inc.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package config
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+func TestMapSet(t *testing.T) {
+	var m Map[string, Duration]
+	checkOK(t, nil, !m.IsSet())
+	checkOK(t, m.Set("a=1s, b=2s"), m.IsSet() && len(m.Values) == 2)
+	checkOK(t, m.Set("c=3s"), len(m.Values) == 3 && m.Values["c"].Duration == 3*time.Second)
+	checkOK(t, nil, m.String() == "a=1s,b=2s,c=3s")
+	checkErr(t, m.Set("d"))
+	checkErr(t, m.Set("d=x"))
+
+	var s Map[string, string]
+	checkOK(t, s.Set(`k=a\,b,l=c`), s.Values["k"] == "a,b" && s.Values["l"] == "c")
+	checkOK(t, nil, s.String() == `k=a\,b,l=c`)
+}
+
+func TestMapMarshal(t *testing.T) {
+	checkJSON(t, Map[string, Duration]{Values: map[string]Duration{"a": {time.Second}}}, `{"a":"1s"}`)
+	checkYAML(t, Map[string, int]{Values: map[string]int{"a": 1, "b": 2}}, "a: 1\nb: 2")
+
+	var m Map[int, string]
+	checkOK(t, json.Unmarshal([]byte(`{"1":"x"}`), &m), m.Values[1] == "x")
+	checkOK(t, yaml.Unmarshal([]byte(`2: y`), &m), m.Values[2] == "y" && len(m.Values) == 1)
+	checkErr(t, json.Unmarshal([]byte(`{"x":"y"}`), &m))
+}
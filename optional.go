@@ -0,0 +1,203 @@
+/*
+ * Copyright 2018 Farsight Security, Inc.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+)
+
+type optionalState int
+
+const (
+	optionalUnset optionalState = iota
+	optionalNull
+	optionalValue
+)
+
+// Optional wraps a value of type T, which may be any of the types in this
+// package or a primitive string, bool, integer or floating point type, so
+// that a configuration can explicitly disable a setting rather than leave
+// it at its default.
+//
+// An Optional is in one of three states: unset, explicitly null, or holding
+// a concrete value. The JSON value null, the YAML value off, and the
+// strings "null" and "off" given to Set all select the null state. For
+// boolean T, off is parsed as false rather than null. The YAML decoder
+// does not pass null values to UnmarshalYAML, so a YAML null selects the
+// null state only when the document is loaded with LoadYAML or
+// config.UnmarshalYAML; yaml.Unmarshal alone leaves the Optional unset.
+//
+//      type myConfig struct {
+//              Timeout config.Optional[config.Duration]
+//      }
+//
+// With `timeout: off`, cfg.Timeout.IsNull() is true, with `timeout: 5s`,
+// cfg.Timeout.Get() returns the Duration, and with timeout absent,
+// cfg.Timeout.IsSet() is false.
+//
+// An unset Optional marshals as null, which reloads as an explicit null.
+// To omit unset Optionals when writing a config back out, tag the field
+// with `yaml:",omitempty"` and, with Go 1.24 or later, `json:",omitzero"`,
+// both of which use IsZero:
+//
+//      Timeout config.Optional[config.Duration] `json:"timeout,omitzero" yaml:"timeout,omitempty"`
+type Optional[T any] struct {
+	Value T
+	state optionalState
+}
+
+// Get returns the Optional's value and true if it holds a concrete value,
+// or the zero value of T and false otherwise.
+func (o Optional[T]) Get() (T, bool) {
+	if o.state != optionalValue {
+		var zero T
+		return zero, false
+	}
+	return o.Value, true
+}
+
+// Or returns the Optional's value if it holds a concrete value, or def
+// otherwise.
+func (o Optional[T]) Or(def T) T {
+	if v, ok := o.Get(); ok {
+		return v
+	}
+	return def
+}
+
+// IsSet returns true if the Optional was assigned a value or null with Set
+// or by unmarshaling.
+func (o Optional[T]) IsSet() bool {
+	return o.state != optionalUnset
+}
+
+func (o *Optional[T]) elems() reflect.Value {
+	if o.state != optionalValue {
+		return reflect.Value{}
+	}
+	return reflect.ValueOf(&o.Value).Elem()
+}
+
+// IsZero returns true if the Optional is unset, so that it is omitted from
+// output by the omitempty YAML and omitzero JSON field options. A null
+// Optional is not zero, and is written out as null.
+func (o Optional[T]) IsZero() bool {
+	return o.state == optionalUnset
+}
+
+// IsNull returns true if the Optional was explicitly set to null or off.
+func (o Optional[T]) IsNull() bool {
+	return o.state == optionalNull
+}
+
+func (o *Optional[T]) setNull() {
+	var zero T
+	o.Value = zero
+	o.state = optionalNull
+}
+
+func (o *Optional[T]) isBool() bool {
+	return reflect.TypeOf(&o.Value).Elem().Kind() == reflect.Bool
+}
+
+// String satisfies the flag.Value interface. It returns "off" if the
+// Optional is null and the empty string if it is unset.
+func (o *Optional[T]) String() string {
+	switch o.state {
+	case optionalNull:
+		return "off"
+	case optionalValue:
+		return formatValue(&o.Value)
+	}
+	return ""
+}
+
+// Set satisfies the flag.Value interface. The values "null" and "off" set
+// the Optional to null, other values are parsed as T.
+func (o *Optional[T]) Set(s string) error {
+	if strings.EqualFold(s, "null") || (strings.EqualFold(s, "off") && !o.isBool()) {
+		o.setNull()
+		return nil
+	}
+	if err := setValue(&o.Value, s); err != nil {
+		return err
+	}
+	o.state = optionalValue
+	return nil
+}
+
+// MarshalJSON satisfies the json.Marshaler interface. Unset and null
+// Optionals are marshaled as null.
+func (o Optional[T]) MarshalJSON() ([]byte, error) {
+	if o.state != optionalValue {
+		return []byte("null"), nil
+	}
+	return json.Marshal(&o.Value)
+}
+
+// MarshalYAML satisfies the yaml.Marshaler interface. Unset Optionals
+// are marshaled as null, and null Optionals as off, which reloads as null
+// with yaml.Unmarshal as well as config.UnmarshalYAML. A null
+// Optional[bool] is marshaled as null.
+func (o Optional[T]) MarshalYAML() (interface{}, error) {
+	if o.state == optionalNull && !o.isBool() {
+		return "off", nil
+	}
+	if o.state != optionalValue {
+		return nil, nil
+	}
+	return &o.Value, nil
+}
+
+// UnmarshalJSON satisfies the json.Unmarshaler interface
+func (o *Optional[T]) UnmarshalJSON(b []byte) error {
+	b = bytes.TrimSpace(b)
+	if bytes.Equal(b, []byte("null")) ||
+		(!o.isBool() && bytes.EqualFold(b, []byte(`"off"`))) {
+		o.setNull()
+		return nil
+	}
+	if err := json.Unmarshal(b, &o.Value); err != nil {
+		return err
+	}
+	o.state = optionalValue
+	return nil
+}
+
+// UnmarshalYAML satisfies the yaml.Unmarshaler interface
+func (o *Optional[T]) UnmarshalYAML(u func(interface{}) error) error {
+	var raw interface{}
+	if err := u(&raw); err != nil {
+		return err
+	}
+	switch r := raw.(type) {
+	case nil:
+		o.setNull()
+		return nil
+	case bool:
+		// YAML 1.1 parses an unquoted off as false.
+		if !r && !o.isBool() {
+			o.setNull()
+			return nil
+		}
+	case string:
+		if strings.EqualFold(r, "off") && !o.isBool() {
+			o.setNull()
+			return nil
+		}
+	}
+	if err := u(&o.Value); err != nil {
+		return err
+	}
+	o.state = optionalValue
+	return nil
+}
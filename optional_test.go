@@ -0,0 +1,85 @@
+/*
+ * Copyright 2018 Farsight Security, Inc.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package config
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+func TestOptionalSet(t *testing.T) {
+	var o Optional[Duration]
+	checkOK(t, nil, !o.IsSet() && !o.IsNull() && o.IsZero() && o.String() == "")
+	checkOK(t, o.Set("off"), o.IsSet() && o.IsNull() && !o.IsZero())
+	checkOK(t, nil, o.String() == "off")
+	checkOK(t, o.Set("0s"), o.IsSet() && !o.IsNull())
+	v, ok := o.Get()
+	checkOK(t, nil, ok && v.Duration == 0 && o.String() == "0s")
+	checkOK(t, o.Set("NULL"), o.IsNull())
+	checkOK(t, nil, o.Or(Duration{time.Second}).Duration == time.Second)
+	checkErr(t, o.Set("forever"))
+
+	var b Optional[bool]
+	checkOK(t, b.Set("false"), !b.IsNull() && b.IsSet() && !b.Value)
+	checkOK(t, b.Set("null"), b.IsNull())
+
+	var n Optional[int]
+	checkOK(t, n.Set("42"), n.Or(0) == 42)
+	checkErr(t, n.Set("x"))
+}
+
+type optionalConfig struct {
+	Timeout Optional[Duration] `json:"timeout" yaml:"timeout,omitempty"`
+	Enabled Optional[bool]     `json:"enabled" yaml:"enabled,omitempty"`
+}
+
+func TestOptionalMarshal(t *testing.T) {
+	checkJSON(t, optionalConfig{Timeout: Optional[Duration]{Value: Duration{time.Minute}, state: optionalValue}},
+		`{"timeout":"1m0s","enabled":null}`)
+	checkYAML(t, optionalConfig{}, `{}`)
+	checkYAML(t, optionalConfig{Timeout: Optional[Duration]{state: optionalNull}}, `timeout: "off"`)
+
+	var c optionalConfig
+	checkOK(t, yaml.Unmarshal([]byte("timeout: off\nenabled: off"), &c),
+		c.Timeout.IsNull() && c.Enabled.IsSet() && !c.Enabled.IsNull())
+	c = optionalConfig{}
+	checkOK(t, json.Unmarshal([]byte(`{"timeout":"off","enabled":null}`), &c),
+		c.Timeout.IsNull() && c.Enabled.IsNull())
+	c = optionalConfig{}
+	checkOK(t, json.Unmarshal([]byte(`{"timeout":"5s"}`), &c),
+		c.Timeout.Or(Duration{}).Duration == 5*time.Second && !c.Enabled.IsSet())
+	checkErr(t, json.Unmarshal([]byte(`{"timeout":"5"}`), &c))
+}
+
+func TestOptionalYAMLNull(t *testing.T) {
+	type inner struct {
+		Limit Optional[int] `yaml:"limit"`
+	}
+	var c struct {
+		optionalConfig `yaml:",inline"`
+		Inner          inner
+		List           []inner
+		Map            map[string]Optional[string]
+		Values         List[Optional[int]]
+	}
+	doc := "timeout: null\nenabled: ~\ninner:\n  limit:\nlist: [{limit: 1}, {limit: null}]\n" +
+		"map: {a: x, b: null}\nvalues: [1, null]\n"
+	checkOK(t, UnmarshalYAML([]byte(doc), &c),
+		c.Timeout.IsNull() && c.Enabled.IsNull() && c.Inner.Limit.IsNull() &&
+			c.List[0].Limit.Or(0) == 1 && c.List[1].Limit.IsNull() &&
+			c.Map["a"].Or("") == "x" && c.Map["b"].IsNull() &&
+			c.Values.Len() == 2 && c.Values.Values[1].IsNull())
+
+	c.Timeout, c.Inner = Optional[Duration]{}, inner{}
+	checkOK(t, yaml.Unmarshal([]byte(doc), &c), !c.Timeout.IsSet() && !c.Inner.Limit.IsSet())
+	checkOK(t, UnmarshalYAML([]byte("timeout: 5s"), &c), !c.Timeout.IsNull() && c.Timeout.IsSet())
+}
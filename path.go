@@ -0,0 +1,391 @@
+/*
+ * Copyright 2018 Farsight Security, Inc.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+)
+
+// FilePath is the path of a file. When set, a leading "~" or "~user" is
+// replaced with the home directory of the current or named user, and
+// environment variable references such as "$STATE_DIR" or "${STATE_DIR}"
+// are expanded. Marshaling a FilePath gives the path as originally
+// written. The empty string leaves a FilePath unset, whatever its options,
+// and an unset FilePath marshals as the empty string.
+//
+// The remaining fields are options which may be set before unmarshaling or
+// calling Set, e.g.:
+//
+//      cfg := Config{
+//              PIDFile: config.FilePath{Create: true, Writable: true},
+//              KeyFile: config.FilePath{MustExist: true, Secret: true},
+//      }
+//
+// If RelativeToConfig is set, a relative path is taken as relative to the
+// directory of the config file loaded by LoadYAML or LoadJSON rather than
+// the working directory.
+//
+// The file is checked according to the options by Set or, when unmarshaled,
+// by LoadYAML, LoadJSON, or Validate:
+//
+//      MustExist   the file must exist
+//      Regular     the file, if it exists, must be a regular file
+//      Writable    the file (or, if it does not exist, its directory) must
+//                  be writable
+//      Create      the file is created if it does not exist, with Mode (by
+//                  default, 0644, or 0600 if Secret is set)
+//      Secret      the file, if it exists, must not be readable by all users
+type FilePath struct {
+	Path             string
+	MustExist        bool
+	Regular          bool
+	Writable         bool
+	Create           bool
+	Mode             os.FileMode
+	Secret           bool
+	RelativeToConfig bool
+	source           string
+	unchecked        bool
+}
+
+// DirPath is the path of a directory, expanded as described for FilePath.
+// A DirPath which exists must be a directory. The options, checked as for
+// FilePath, are:
+//
+//      MustExist   the directory must exist
+//      Writable    the directory must be writable
+//      Create      the directory and any missing parents are created if it
+//                  does not exist, with Mode (by default, 0755, or 0700 if
+//                  Secret is set)
+//      Secret      the directory, if it exists, must not be readable by all
+//                  users
+type DirPath struct {
+	Path             string
+	MustExist        bool
+	Writable         bool
+	Create           bool
+	Mode             os.FileMode
+	Secret           bool
+	RelativeToConfig bool
+	source           string
+	unchecked        bool
+}
+
+type errPathInvalid struct{ path, reason string }
+
+func (e errPathInvalid) Error() string {
+	return fmt.Sprintf("Invalid path '%s': %s", e.path, e.reason)
+}
+
+// expandPath replaces a leading "~" or "~user" with a home directory, and
+// expands environment variable references.
+func expandPath(s string) (string, error) {
+	s = os.ExpandEnv(s)
+	if !strings.HasPrefix(s, "~") {
+		return s, nil
+	}
+	name, rest := s[1:], ""
+	if i := strings.IndexAny(name, `/\`); i >= 0 {
+		name, rest = name[:i], name[i:]
+	}
+	var home string
+	if name == "" {
+		var err error
+		if home, err = os.UserHomeDir(); err != nil {
+			return "", errPathInvalid{s, err.Error()}
+		}
+	} else {
+		u, err := user.Lookup(name)
+		if err != nil {
+			return "", errPathInvalid{s, err.Error()}
+		}
+		home = u.HomeDir
+	}
+	return home + rest, nil
+}
+
+func resolvePath(p, dir string, relative bool) string {
+	if relative && dir != "" && p != "" && !filepath.IsAbs(p) {
+		return filepath.Join(dir, p)
+	}
+	return p
+}
+
+func checkSecret(path string, fi os.FileInfo) error {
+	if fi.Mode().Perm()&0004 != 0 {
+		return errPathInvalid{path, "readable by all users"}
+	}
+	return nil
+}
+
+// Set satisfies the flag.Value interface, expanding and checking the path.
+// The empty string leaves the FilePath unset, and is not checked.
+func (f *FilePath) Set(s string) error {
+	if err := f.set(s); err != nil {
+		return err
+	}
+	f.unchecked = false
+	return f.check()
+}
+
+func (f *FilePath) set(s string) error {
+	p, err := expandPath(s)
+	if err != nil {
+		return err
+	}
+	f.Path, f.source = p, s
+	return nil
+}
+
+func (f *FilePath) checkLoaded(dir string) error {
+	if !f.unchecked {
+		return nil
+	}
+	f.unchecked = false
+	f.Path = resolvePath(f.Path, dir, f.RelativeToConfig)
+	return f.check()
+}
+
+func (f *FilePath) check() error {
+	if f.source == "" {
+		return nil
+	}
+	fi, err := os.Stat(f.Path)
+	if os.IsNotExist(err) && f.Create {
+		mode := f.Mode
+		if mode == 0 {
+			mode = 0644
+			if f.Secret {
+				mode = 0600
+			}
+		}
+		var fh *os.File
+		if fh, err = os.OpenFile(f.Path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, mode); err != nil {
+			return err
+		}
+		fh.Close()
+		fi, err = os.Stat(f.Path)
+	}
+	if os.IsNotExist(err) {
+		if f.MustExist {
+			return errPathInvalid{f.Path, "file does not exist"}
+		}
+		if f.Writable {
+			return checkDirWritable(f.Path, filepath.Dir(f.Path))
+		}
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if fi.IsDir() || (f.Regular && !fi.Mode().IsRegular()) {
+		return errPathInvalid{f.Path, "not a regular file"}
+	}
+	if f.Secret {
+		if err = checkSecret(f.Path, fi); err != nil {
+			return err
+		}
+	}
+	if f.Writable {
+		fh, err := os.OpenFile(f.Path, os.O_WRONLY|os.O_APPEND, 0)
+		if err != nil {
+			return errPathInvalid{f.Path, "not writable"}
+		}
+		fh.Close()
+	}
+	return nil
+}
+
+// checkDirWritable checks that a file can be created in dir.
+func checkDirWritable(path, dir string) error {
+	f, err := os.CreateTemp(dir, ".writable-")
+	if err != nil {
+		return errPathInvalid{path, "not writable"}
+	}
+	f.Close()
+	os.Remove(f.Name())
+	return nil
+}
+
+// String returns the expanded path.
+func (f FilePath) String() string {
+	return f.Path
+}
+
+// IsSet returns true if the FilePath was assigned a value with Set or by
+// unmarshaling.
+func (f FilePath) IsSet() bool {
+	return f.source != ""
+}
+
+// MarshalJSON satisfies the json.Marshaler interface
+func (f FilePath) MarshalJSON() ([]byte, error) {
+	return json.Marshal(f.source)
+}
+
+// MarshalYAML satisfies the yaml.Marshaler interface
+func (f FilePath) MarshalYAML() (interface{}, error) {
+	return f.source, nil
+}
+
+// UnmarshalJSON satisfies the json.Unmarshaler interface. The path is
+// checked by LoadJSON or Validate.
+func (f *FilePath) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	if err := f.set(s); err != nil {
+		return err
+	}
+	f.unchecked = true
+	return nil
+}
+
+// UnmarshalYAML satisfies the yaml.Unmarshaler interface. The path is
+// checked by LoadYAML or Validate.
+func (f *FilePath) UnmarshalYAML(u func(interface{}) error) error {
+	var s string
+	if err := u(&s); err != nil {
+		return err
+	}
+	if err := f.set(s); err != nil {
+		return err
+	}
+	f.unchecked = true
+	return nil
+}
+
+// Set satisfies the flag.Value interface, expanding and checking the path.
+// The empty string leaves the DirPath unset, and is not checked.
+func (d *DirPath) Set(s string) error {
+	if err := d.set(s); err != nil {
+		return err
+	}
+	d.unchecked = false
+	return d.check()
+}
+
+func (d *DirPath) set(s string) error {
+	p, err := expandPath(s)
+	if err != nil {
+		return err
+	}
+	d.Path, d.source = p, s
+	return nil
+}
+
+func (d *DirPath) checkLoaded(dir string) error {
+	if !d.unchecked {
+		return nil
+	}
+	d.unchecked = false
+	d.Path = resolvePath(d.Path, dir, d.RelativeToConfig)
+	return d.check()
+}
+
+func (d *DirPath) check() error {
+	if d.source == "" {
+		return nil
+	}
+	fi, err := os.Stat(d.Path)
+	if os.IsNotExist(err) && d.Create {
+		mode := d.Mode
+		if mode == 0 {
+			mode = 0755
+			if d.Secret {
+				mode = 0700
+			}
+		}
+		if err = os.MkdirAll(d.Path, mode); err != nil {
+			return err
+		}
+		fi, err = os.Stat(d.Path)
+	}
+	if os.IsNotExist(err) {
+		if d.MustExist || d.Writable {
+			return errPathInvalid{d.Path, "directory does not exist"}
+		}
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !fi.IsDir() {
+		return errPathInvalid{d.Path, "not a directory"}
+	}
+	if d.Secret {
+		if err = checkSecret(d.Path, fi); err != nil {
+			return err
+		}
+	}
+	if d.Writable {
+		return checkDirWritable(d.Path, d.Path)
+	}
+	return nil
+}
+
+// Join returns the path of name within the directory.
+func (d DirPath) Join(name ...string) string {
+	return filepath.Join(append([]string{d.Path}, name...)...)
+}
+
+// String returns the expanded path.
+func (d DirPath) String() string {
+	return d.Path
+}
+
+// IsSet returns true if the DirPath was assigned a value with Set or by
+// unmarshaling.
+func (d DirPath) IsSet() bool {
+	return d.source != ""
+}
+
+// MarshalJSON satisfies the json.Marshaler interface
+func (d DirPath) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.source)
+}
+
+// MarshalYAML satisfies the yaml.Marshaler interface
+func (d DirPath) MarshalYAML() (interface{}, error) {
+	return d.source, nil
+}
+
+// UnmarshalJSON satisfies the json.Unmarshaler interface. The path is
+// checked by LoadJSON or Validate.
+func (d *DirPath) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	if err := d.set(s); err != nil {
+		return err
+	}
+	d.unchecked = true
+	return nil
+}
+
+// UnmarshalYAML satisfies the yaml.Unmarshaler interface. The path is
+// checked by LoadYAML or Validate.
+func (d *DirPath) UnmarshalYAML(u func(interface{}) error) error {
+	var s string
+	if err := u(&s); err != nil {
+		return err
+	}
+	if err := d.set(s); err != nil {
+		return err
+	}
+	d.unchecked = true
+	return nil
+}
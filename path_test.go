@@ -0,0 +1,120 @@
+/*
+ * Copyright 2018 Farsight Security, Inc.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFilePath(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("PATH_TEST_DIR", dir)
+
+	f := FilePath{Create: true, Secret: true}
+	checkOK(t, f.Set("$PATH_TEST_DIR/app.pid"), f.Path == filepath.Join(dir, "app.pid"))
+	fi, err := os.Stat(f.Path)
+	checkOK(t, err, fi.Mode().Perm() == 0600)
+	checkJSON(t, f, `"$PATH_TEST_DIR/app.pid"`)
+
+	checkErr(t, (&FilePath{MustExist: true}).Set(filepath.Join(dir, "missing")))
+	checkErr(t, (&FilePath{}).Set(dir))
+	checkOK(t, os.WriteFile(filepath.Join(dir, "public"), nil, 0644), true)
+	checkErr(t, (&FilePath{Secret: true}).Set(filepath.Join(dir, "public")))
+	checkOK(t, (&FilePath{Writable: true}).Set(filepath.Join(dir, "new")), true)
+
+	home, err := os.UserHomeDir()
+	if err == nil {
+		checkOK(t, f.Set("~/x"), f.Path == home+"/x")
+	}
+}
+
+func TestDirPath(t *testing.T) {
+	dir := t.TempDir()
+	d := DirPath{Create: true}
+	checkOK(t, d.Set(filepath.Join(dir, "a/b")), d.Join("c") == filepath.Join(dir, "a/b/c"))
+	fi, err := os.Stat(d.Path)
+	checkOK(t, err, fi.IsDir())
+	checkErr(t, (&DirPath{MustExist: true}).Set(filepath.Join(dir, "missing")))
+	checkOK(t, os.WriteFile(filepath.Join(dir, "file"), nil, 0644), true)
+	checkErr(t, (&DirPath{}).Set(filepath.Join(dir, "file")))
+}
+
+func TestPathUnset(t *testing.T) {
+	f := FilePath{MustExist: true, Create: true}
+	checkOK(t, f.Set(""), !f.IsSet())
+	d := DirPath{MustExist: true, Create: true}
+	checkOK(t, d.Set(""), !d.IsSet())
+
+	// An unset path reloads as unset, whatever its options.
+	c := struct {
+		Key  FilePath `json:"key"`
+		Data DirPath  `json:"data"`
+	}{FilePath{MustExist: true}, DirPath{MustExist: true}}
+	checkJSON(t, c, `{"key":"","data":""}`)
+	name := filepath.Join(t.TempDir(), "app.json")
+	checkOK(t, os.WriteFile(name, []byte(`{"key":"","data":""}`), 0644), true)
+	checkOK(t, LoadJSON(&c, name, true), !c.Key.IsSet() && !c.Data.IsSet())
+}
+
+func TestPathLoad(t *testing.T) {
+	dir := t.TempDir()
+	checkOK(t, os.Mkdir(filepath.Join(dir, "data"), 0755), true)
+	name := filepath.Join(dir, "app.yaml")
+	checkOK(t, os.WriteFile(name, []byte("key: key.pem\ndata: data\n"), 0644), true)
+
+	cfg := struct {
+		Key  FilePath `yaml:"key"`
+		Data DirPath  `yaml:"data"`
+	}{
+		Key:  FilePath{RelativeToConfig: true, MustExist: true},
+		Data: DirPath{RelativeToConfig: true, MustExist: true},
+	}
+	err := LoadYAML(&cfg, name, true)
+	checkOK(t, nil, err != nil && strings.Contains(err.Error(), "key"))
+
+	checkOK(t, os.WriteFile(filepath.Join(dir, "key.pem"), nil, 0600), true)
+	checkOK(t, LoadYAML(&cfg, name, true), cfg.Key.Path == filepath.Join(dir, "key.pem") &&
+		cfg.Data.Path == filepath.Join(dir, "data"))
+}
+
+func TestPathContainers(t *testing.T) {
+	dir := t.TempDir()
+	checkOK(t, os.WriteFile(filepath.Join(dir, "a.pem"), nil, 0600), true)
+	name := filepath.Join(dir, "app.yaml")
+
+	type config struct {
+		Files  List[FilePath]        `yaml:"files"`
+		Named  Map[string, FilePath] `yaml:"named"`
+		Backup Optional[DirPath]     `yaml:"backup"`
+	}
+	newConfig := func() config {
+		var c config
+		c.Files.Elem = FilePath{RelativeToConfig: true, MustExist: true}
+		c.Named.Elem = FilePath{RelativeToConfig: true, MustExist: true}
+		c.Backup.Value = DirPath{RelativeToConfig: true, Create: true}
+		return c
+	}
+
+	checkOK(t, os.WriteFile(name, []byte("files: [a.pem]\nnamed: {a: a.pem}\nbackup: backup\n"), 0644), true)
+	c := newConfig()
+	checkOK(t, LoadYAML(&c, name, true), c.Files.Values[0].Path == filepath.Join(dir, "a.pem") &&
+		c.Named.Values["a"].Path == filepath.Join(dir, "a.pem"))
+	_, err := os.Stat(filepath.Join(dir, "backup"))
+	checkOK(t, err, true)
+
+	for _, s := range []string{"files: [a.pem, b.pem]\n", "named: {b: b.pem}\n"} {
+		checkOK(t, os.WriteFile(name, []byte(s), 0644), true)
+		c = newConfig()
+		err = LoadYAML(&c, name, true)
+		checkOK(t, nil, err != nil && strings.Contains(err.Error(), "b.pem"))
+	}
+}
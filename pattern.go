@@ -0,0 +1,431 @@
+/*
+ * Copyright 2018 Farsight Security, Inc.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"regexp/syntax"
+	"strings"
+)
+
+// Regexp provides JSON, YAML and flag support for regular expressions,
+// which are compiled with regexp.Compile when set or unmarshaled and
+// marshal back to their source. As with regexp.MatchString, a Regexp
+// matches any string containing a match unless anchored with "^" and "$".
+type Regexp struct{ *regexp.Regexp }
+
+type errRegexpInvalid struct {
+	expr string
+	err  error
+}
+
+func (e errRegexpInvalid) Error() string {
+	var se *syntax.Error
+	if errors.As(e.err, &se) {
+		return fmt.Sprintf("Invalid regular expression '%s': %s in '%s'",
+			e.expr, se.Code, se.Expr)
+	}
+	return fmt.Sprintf("Invalid regular expression '%s': %v", e.expr, e.err)
+}
+
+// Set satisfies the flag.Value interface. The empty string leaves the
+// Regexp unset, so that an unset Regexp, which marshals as the empty
+// string, is unset when reloaded.
+func (r *Regexp) Set(s string) error {
+	if s == "" {
+		r.Regexp = nil
+		return nil
+	}
+	re, err := regexp.Compile(s)
+	if err != nil {
+		return errRegexpInvalid{s, err}
+	}
+	r.Regexp = re
+	return nil
+}
+
+// String returns the source of the regular expression.
+func (r Regexp) String() string {
+	if r.Regexp == nil {
+		return ""
+	}
+	return r.Regexp.String()
+}
+
+// IsSet returns true if the Regexp was assigned a value with Set or by
+// unmarshaling.
+func (r Regexp) IsSet() bool {
+	return r.Regexp != nil
+}
+
+// MarshalJSON satisfies the json.Marshaler interface
+func (r Regexp) MarshalJSON() ([]byte, error) {
+	return json.Marshal(r.String())
+}
+
+// MarshalYAML satisfies the yaml.Marshaler interface
+func (r Regexp) MarshalYAML() (interface{}, error) {
+	return r.String(), nil
+}
+
+// UnmarshalJSON satisfies the json.Unmarshaler interface
+func (r *Regexp) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	return r.Set(s)
+}
+
+// UnmarshalYAML satisfies the yaml.Unmarshaler interface
+func (r *Regexp) UnmarshalYAML(u func(interface{}) error) error {
+	var s string
+	if err := u(&s); err != nil {
+		return err
+	}
+	return r.Set(s)
+}
+
+// Glob is a shell-style wildcard pattern, matched against the whole of a
+// string, e.g., "*.example.com" or "/var/log/**/*.log". In a Glob:
+//
+//      *       matches any sequence of characters other than "/"
+//      **      matches any sequence of characters, including "/"
+//      ?       matches any single character other than "/"
+//      [abc]   matches any one of the characters in the brackets; ranges
+//              such as [a-z] and negation with [!abc] or [^abc] are allowed
+//      {a,b}   matches any one of the comma-separated alternatives
+//      \c      matches the character c literally
+//
+// A "**/" matches zero or more leading directories, so "**/*.log" matches
+// "a.log" as well as "x/y/a.log". Globs are compiled when set or
+// unmarshaled, and marshal back to their source.
+type Glob struct {
+	pattern string
+	re      *regexp.Regexp
+}
+
+type errGlobInvalid struct{ pattern, reason string }
+
+func (e errGlobInvalid) Error() string {
+	return fmt.Sprintf("Invalid glob pattern '%s': %s", e.pattern, e.reason)
+}
+
+// Set satisfies the flag.Value interface. The empty string leaves the
+// Glob unset, as for Regexp.
+func (g *Glob) Set(s string) error {
+	if s == "" {
+		*g = Glob{}
+		return nil
+	}
+	expr, err := globRegexp(s)
+	if err != nil {
+		return err
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return errGlobInvalid{s, err.Error()}
+	}
+	*g = Glob{pattern: s, re: re}
+	return nil
+}
+
+// globRegexp translates a Glob pattern into an anchored regular
+// expression.
+func globRegexp(pattern string) (string, error) {
+	var b strings.Builder
+	b.WriteString("^")
+	p := []rune(pattern)
+	depth := 0
+	for i := 0; i < len(p); i++ {
+		switch c := p[i]; c {
+		case '*':
+			if i+1 < len(p) && p[i+1] == '*' {
+				i++
+				if i+1 < len(p) && p[i+1] == '/' {
+					i++
+					b.WriteString("(?:.*/)?")
+				} else {
+					b.WriteString(".*")
+				}
+			} else {
+				b.WriteString("[^/]*")
+			}
+		case '?':
+			b.WriteString("[^/]")
+		case '[':
+			j := i + 1
+			if j < len(p) && (p[j] == '!' || p[j] == '^') {
+				j++
+			}
+			if j < len(p) && p[j] == ']' {
+				j++
+			}
+			for j < len(p) && p[j] != ']' {
+				j++
+			}
+			if j >= len(p) {
+				return "", errGlobInvalid{pattern, "unterminated character class"}
+			}
+			b.WriteString("[")
+			k := i + 1
+			if p[k] == '!' || p[k] == '^' {
+				b.WriteString("^")
+				k++
+			}
+			for ; k < j; k++ {
+				if p[k] == '\\' || p[k] == '[' || p[k] == ']' {
+					b.WriteRune('\\')
+				}
+				b.WriteRune(p[k])
+			}
+			b.WriteString("]")
+			i = j
+		case '{':
+			depth++
+			b.WriteString("(?:")
+		case ',':
+			if depth > 0 {
+				b.WriteString("|")
+			} else {
+				b.WriteString(",")
+			}
+		case '}':
+			if depth == 0 {
+				return "", errGlobInvalid{pattern, "unmatched '}'"}
+			}
+			depth--
+			b.WriteString(")")
+		case '\\':
+			if i+1 >= len(p) {
+				return "", errGlobInvalid{pattern, "trailing '\\'"}
+			}
+			i++
+			b.WriteString(regexp.QuoteMeta(string(p[i])))
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	if depth > 0 {
+		return "", errGlobInvalid{pattern, "unterminated '{'"}
+	}
+	b.WriteString("$")
+	return b.String(), nil
+}
+
+// Match returns true if s matches the Glob.
+func (g Glob) Match(s string) bool {
+	return g.re != nil && g.re.MatchString(s)
+}
+
+// MatchString returns true if s matches the Glob, as Match does.
+func (g Glob) MatchString(s string) bool {
+	return g.Match(s)
+}
+
+// String returns the Glob's pattern.
+func (g Glob) String() string {
+	return g.pattern
+}
+
+// IsSet returns true if the Glob was assigned a value with Set or by
+// unmarshaling.
+func (g Glob) IsSet() bool {
+	return g.re != nil
+}
+
+// MarshalJSON satisfies the json.Marshaler interface
+func (g Glob) MarshalJSON() ([]byte, error) {
+	return json.Marshal(g.pattern)
+}
+
+// MarshalYAML satisfies the yaml.Marshaler interface
+func (g Glob) MarshalYAML() (interface{}, error) {
+	return g.pattern, nil
+}
+
+// UnmarshalJSON satisfies the json.Unmarshaler interface
+func (g *Glob) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	return g.Set(s)
+}
+
+// UnmarshalYAML satisfies the yaml.Unmarshaler interface
+func (g *Glob) UnmarshalYAML(u func(interface{}) error) error {
+	var s string
+	if err := u(&s); err != nil {
+		return err
+	}
+	return g.Set(s)
+}
+
+// PatternList is a list of patterns which a string may match, e.g.:
+//
+//      exclude:
+//        - "*.tmp"
+//        - "/var/cache/**"
+//        - 're:^core\.[0-9]+$'
+//
+// A pattern prefixed with "re:" is a Regexp; otherwise it is a Glob, which
+// may also be written with the prefix "glob:".
+//
+// Since patterns may contain commas, each flag or string value is a single
+// pattern. As a flag, the first Set replaces any existing (default or
+// unmarshaled) patterns, and subsequent Sets append to them.
+type PatternList struct {
+	patterns  []pattern
+	set, flag bool
+}
+
+type pattern struct {
+	source string
+	match  func(string) bool
+}
+
+type errPatternEmpty string
+
+func (e errPatternEmpty) Error() string {
+	return fmt.Sprintf("Empty pattern '%s'", string(e))
+}
+
+func parsePattern(s string) (pattern, error) {
+	if strings.HasPrefix(s, "re:") {
+		var r Regexp
+		if err := r.Set(s[len("re:"):]); err != nil {
+			return pattern{}, err
+		}
+		if !r.IsSet() {
+			return pattern{}, errPatternEmpty(s)
+		}
+		return pattern{s, r.MatchString}, nil
+	}
+	var g Glob
+	if err := g.Set(strings.TrimPrefix(s, "glob:")); err != nil {
+		return pattern{}, err
+	}
+	if !g.IsSet() {
+		return pattern{}, errPatternEmpty(s)
+	}
+	return pattern{s, g.Match}, nil
+}
+
+func (l *PatternList) setPatterns(sources []string) error {
+	patterns := make([]pattern, 0, len(sources))
+	for _, s := range sources {
+		p, err := parsePattern(s)
+		if err != nil {
+			return err
+		}
+		patterns = append(patterns, p)
+	}
+	l.patterns = patterns
+	l.set, l.flag = true, false
+	return nil
+}
+
+// Set satisfies the flag.Value interface, adding the single pattern s.
+func (l *PatternList) Set(s string) error {
+	p, err := parsePattern(s)
+	if err != nil {
+		return err
+	}
+	if !l.flag {
+		l.patterns = nil
+	}
+	l.patterns = append(l.patterns, p)
+	l.set, l.flag = true, true
+	return nil
+}
+
+// Match returns true if s matches any of the patterns in the PatternList.
+func (l PatternList) Match(s string) bool {
+	_, ok := l.MatchPattern(s)
+	return ok
+}
+
+// MatchPattern returns the first pattern in the PatternList matching s,
+// and whether any pattern matched.
+func (l PatternList) MatchPattern(s string) (string, bool) {
+	for _, p := range l.patterns {
+		if p.match(s) {
+			return p.source, true
+		}
+	}
+	return "", false
+}
+
+// Patterns returns the source of each pattern in the PatternList.
+func (l PatternList) Patterns() []string {
+	sources := make([]string, len(l.patterns))
+	for i, p := range l.patterns {
+		sources[i] = p.source
+	}
+	return sources
+}
+
+// Len returns the number of patterns in the PatternList.
+func (l PatternList) Len() int {
+	return len(l.patterns)
+}
+
+// String satisfies the flag.Value interface, returning the patterns
+// separated by spaces.
+func (l PatternList) String() string {
+	return strings.Join(l.Patterns(), " ")
+}
+
+// IsSet returns true if the PatternList was assigned a value with Set or
+// by unmarshaling.
+func (l PatternList) IsSet() bool {
+	return l.set
+}
+
+// MarshalJSON satisfies the json.Marshaler interface
+func (l PatternList) MarshalJSON() ([]byte, error) {
+	return json.Marshal(l.Patterns())
+}
+
+// MarshalYAML satisfies the yaml.Marshaler interface
+func (l PatternList) MarshalYAML() (interface{}, error) {
+	return l.Patterns(), nil
+}
+
+// UnmarshalJSON satisfies the json.Unmarshaler interface, accepting a
+// sequence of patterns or a single pattern.
+func (l *PatternList) UnmarshalJSON(b []byte) error {
+	var sources []string
+	if err := json.Unmarshal(b, &sources); err != nil {
+		var s string
+		if json.Unmarshal(b, &s) != nil {
+			return err
+		}
+		sources = []string{s}
+	}
+	return l.setPatterns(sources)
+}
+
+// UnmarshalYAML satisfies the yaml.Unmarshaler interface, accepting a
+// sequence of patterns or a single pattern.
+func (l *PatternList) UnmarshalYAML(u func(interface{}) error) error {
+	var sources []string
+	if err := u(&sources); err != nil {
+		var s string
+		if u(&s) != nil {
+			return err
+		}
+		sources = []string{s}
+	}
+	return l.setPatterns(sources)
+}
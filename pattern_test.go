@@ -0,0 +1,99 @@
+/*
+ * Copyright 2018 Farsight Security, Inc.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package config
+
+import (
+	"encoding/json"
+	"regexp"
+	"testing"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+func TestRegexp(t *testing.T) {
+	var r Regexp
+	checkOK(t, nil, !r.IsSet() && r.String() == "")
+	checkOK(t, r.Set(`^core\.[0-9]+$`), r.IsSet() && r.MatchString("core.12") && !r.MatchString("core.x"))
+	checkOK(t, r.Set(""), !r.IsSet())
+	err := r.Set("a(b")
+	checkOK(t, nil, err != nil && err.Error() ==
+		"Invalid regular expression 'a(b': missing closing ) in 'a(b'")
+
+	checkJSON(t, Regexp{regexp.MustCompile(`a,b{2}`)}, `"a,b{2}"`)
+	checkYAML(t, Regexp{regexp.MustCompile(`^x$`)}, `^x$`)
+	checkJSON(t, Regexp{}, `""`)
+	checkErr(t, json.Unmarshal([]byte(`"["`), &r))
+}
+
+func TestGlob(t *testing.T) {
+	for _, c := range []struct {
+		pattern string
+		match   []string
+		nomatch []string
+	}{
+		{"*.example.com", []string{"www.example.com", ".example.com"}, []string{"example.com", "a/b.example.com"}},
+		{"/var/log/**/*.log", []string{"/var/log/a.log", "/var/log/x/y/a.log"}, []string{"/var/log/a.txt"}},
+		{"**/*.log", []string{"a.log", "x/y/a.log"}, []string{"a.txt"}},
+		{"file?.txt", []string{"file1.txt"}, []string{"file10.txt", "file/.txt"}},
+		{"[a-c]x[!0-9]", []string{"axy", "cx_"}, []string{"dxy", "ax1"}},
+		{"[^a]", []string{"b"}, []string{"a"}},
+		{"*.{jpg,png}", []string{"a.jpg", "b.png"}, []string{"c.gif", "a.{jpg,png}"}},
+		{`a\*b`, []string{"a*b"}, []string{"axb"}},
+		{"a.b+c", []string{"a.b+c"}, []string{"axbbc"}},
+	} {
+		var g Glob
+		if err := g.Set(c.pattern); err != nil {
+			t.Errorf("%s: %v", c.pattern, err)
+			continue
+		}
+		for _, s := range c.match {
+			if !g.Match(s) {
+				t.Errorf("%s should match %s", c.pattern, s)
+			}
+		}
+		for _, s := range c.nomatch {
+			if g.MatchString(s) {
+				t.Errorf("%s should not match %s", c.pattern, s)
+			}
+		}
+	}
+
+	var g Glob
+	for _, s := range []string{"[abc", "{a,b", "a\\", "a}"} {
+		checkErr(t, g.Set(s))
+	}
+	checkOK(t, g.Set(""), !g.IsSet() && !g.Match(""))
+	checkOK(t, g.Set("*.go"), g.String() == "*.go")
+	checkJSON(t, g, `"*.go"`)
+	checkYAML(t, g, `'*.go'`)
+	checkJSON(t, Glob{}, `""`)
+}
+
+func TestPatternList(t *testing.T) {
+	var l PatternList
+	checkOK(t, yaml.Unmarshal([]byte(`
+- "*.tmp"
+- "/var/cache/**"
+- 're:^core\.[0-9]+$'
+`), &l), l.IsSet() && l.Len() == 3)
+
+	p, ok := l.MatchPattern("core.123")
+	checkOK(t, nil, ok && p == `re:^core\.[0-9]+$`)
+	checkOK(t, nil, l.Match("x.tmp") && l.Match("/var/cache/a/b") && !l.Match("a/x.tmp"))
+	checkYAML(t, l, "- '*.tmp'\n- /var/cache/**\n- re:^core\\.[0-9]+$")
+
+	checkOK(t, json.Unmarshal([]byte(`"glob:{a,b}.c"`), &l), l.Len() == 1 && l.Match("a.c"))
+	checkErr(t, json.Unmarshal([]byte(`["re:("]`), &l))
+	checkErr(t, json.Unmarshal([]byte(`[""]`), &l))
+	checkErr(t, l.Set("re:"))
+
+	// The first Set replaces the unmarshaled patterns.
+	checkOK(t, l.Set("a,b"), l.Len() == 1 && l.Match("a,b"))
+	checkOK(t, l.Set("*.c"), l.Len() == 2 && l.String() == "a,b *.c")
+}
@@ -0,0 +1,348 @@
+/*
+ * Copyright 2018 Farsight Security, Inc.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/user"
+	"strconv"
+	"strings"
+)
+
+// FileMode provides JSON, YAML and flag support for file permissions,
+// written in octal, e.g., "0640" or "2775", or symbolically as shown by
+// ls(1), e.g., "rw-r-----" or "rwxrwsr-x". FileModes are written back in
+// octal.
+type FileMode struct {
+	os.FileMode
+	set bool
+}
+
+type errInvalidFileMode string
+
+func (e errInvalidFileMode) Error() string {
+	return fmt.Sprintf("Invalid file mode '%s': should be octal or symbolic, e.g., 0640 or rw-r-----",
+		string(e))
+}
+
+// Set satisfies the flag.Value interface. The empty string leaves the
+// FileMode unset.
+func (m *FileMode) Set(s string) error {
+	if s == "" {
+		*m = FileMode{}
+		return nil
+	}
+	var mode os.FileMode
+	var err error
+	if len(s) == 9 && strings.Trim(s, "0123456789") != "" {
+		mode, err = parseSymbolicMode(s)
+	} else {
+		mode, err = parseOctalMode(s)
+	}
+	if err != nil {
+		return err
+	}
+	m.FileMode = mode
+	m.set = true
+	return nil
+}
+
+func parseOctalMode(s string) (os.FileMode, error) {
+	n, err := strconv.ParseUint(strings.TrimPrefix(s, "0o"), 8, 32)
+	if err != nil || n > 07777 {
+		return 0, errInvalidFileMode(s)
+	}
+	mode := os.FileMode(n & 0777)
+	if n&04000 != 0 {
+		mode |= os.ModeSetuid
+	}
+	if n&02000 != 0 {
+		mode |= os.ModeSetgid
+	}
+	if n&01000 != 0 {
+		mode |= os.ModeSticky
+	}
+	return mode, nil
+}
+
+// parseSymbolicMode parses the nine permission characters of an ls(1)
+// listing, including "s", "S", "t" and "T" for the setuid, setgid and
+// sticky bits.
+func parseSymbolicMode(s string) (os.FileMode, error) {
+	special := [3]os.FileMode{os.ModeSetuid, os.ModeSetgid, os.ModeSticky}
+	var mode os.FileMode
+	for i := 0; i < 9; i++ {
+		bit := os.FileMode(1) << uint(8-i)
+		switch c := s[i]; {
+		case c == "rwx"[i%3]:
+			mode |= bit
+		case c == '-':
+		case i%3 == 2 && (c == "sst"[i/3]):
+			mode |= bit | special[i/3]
+		case i%3 == 2 && (c == "SST"[i/3]):
+			mode |= special[i/3]
+		default:
+			return 0, errInvalidFileMode(s)
+		}
+	}
+	return mode, nil
+}
+
+// String returns the FileMode in octal, or the empty string if it is
+// unset.
+func (m FileMode) String() string {
+	if !m.set {
+		return ""
+	}
+	n := uint32(m.Perm())
+	if m.FileMode&os.ModeSetuid != 0 {
+		n |= 04000
+	}
+	if m.FileMode&os.ModeSetgid != 0 {
+		n |= 02000
+	}
+	if m.FileMode&os.ModeSticky != 0 {
+		n |= 01000
+	}
+	return fmt.Sprintf("%04o", n)
+}
+
+// IsSet returns true if the FileMode was assigned a value with Set or by
+// unmarshaling.
+func (m FileMode) IsSet() bool {
+	return m.set
+}
+
+// MarshalJSON satisfies the json.Marshaler interface
+func (m FileMode) MarshalJSON() ([]byte, error) {
+	return json.Marshal(m.String())
+}
+
+// MarshalYAML satisfies the yaml.Marshaler interface
+func (m FileMode) MarshalYAML() (interface{}, error) {
+	return m.String(), nil
+}
+
+// UnmarshalJSON satisfies the json.Unmarshaler interface
+func (m *FileMode) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	return m.Set(s)
+}
+
+// UnmarshalYAML satisfies the yaml.Unmarshaler interface
+func (m *FileMode) UnmarshalYAML(u func(interface{}) error) error {
+	var s string
+	if err := u(&s); err != nil {
+		return err
+	}
+	return m.Set(s)
+}
+
+// User is a system user, given by name or numeric ID and resolved with
+// os/user when set. A numeric ID with no matching user is accepted, with
+// an empty Name.
+type User struct {
+	Name string
+	UID  int
+	set  bool
+}
+
+type errUserInvalid struct {
+	name string
+	err  error
+}
+
+func (e errUserInvalid) Error() string {
+	return fmt.Sprintf("Invalid user '%s': %v", e.name, e.err)
+}
+
+// Set satisfies the flag.Value interface. The empty string leaves the
+// User unset.
+func (u *User) Set(s string) error {
+	if s == "" {
+		*u = User{}
+		return nil
+	}
+	if id, ok := parseID(s); ok {
+		*u = User{UID: id, set: true}
+		if usr, err := user.LookupId(s); err == nil {
+			u.Name = usr.Username
+		}
+		return nil
+	}
+	usr, err := user.Lookup(s)
+	if err != nil {
+		return errUserInvalid{s, err}
+	}
+	id, ok := parseID(usr.Uid)
+	if !ok {
+		return errUserInvalid{s, fmt.Errorf("non-numeric uid '%s'", usr.Uid)}
+	}
+	*u = User{Name: usr.Username, UID: id, set: true}
+	return nil
+}
+
+// String returns the User's name, or its UID if it has no name.
+func (u User) String() string {
+	switch {
+	case u.Name != "":
+		return u.Name
+	case u.set:
+		return strconv.Itoa(u.UID)
+	}
+	return ""
+}
+
+// IsSet returns true if the User was assigned a value with Set or by
+// unmarshaling.
+func (u User) IsSet() bool {
+	return u.set
+}
+
+// MarshalJSON satisfies the json.Marshaler interface
+func (u User) MarshalJSON() ([]byte, error) {
+	return json.Marshal(u.String())
+}
+
+// MarshalYAML satisfies the yaml.Marshaler interface
+func (u User) MarshalYAML() (interface{}, error) {
+	return u.String(), nil
+}
+
+// UnmarshalJSON satisfies the json.Unmarshaler interface, accepting a
+// name or a numeric ID as a string or number.
+func (u *User) UnmarshalJSON(b []byte) error {
+	s, err := unmarshalID(b)
+	if err != nil {
+		return err
+	}
+	return u.Set(s)
+}
+
+// UnmarshalYAML satisfies the yaml.Unmarshaler interface
+func (u *User) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var s string
+	if err := unmarshal(&s); err != nil {
+		return err
+	}
+	return u.Set(s)
+}
+
+// Group is a system group, given by name or numeric ID and resolved with
+// os/user when set. A numeric ID with no matching group is accepted, with
+// an empty Name.
+type Group struct {
+	Name string
+	GID  int
+	set  bool
+}
+
+type errGroupInvalid struct {
+	name string
+	err  error
+}
+
+func (e errGroupInvalid) Error() string {
+	return fmt.Sprintf("Invalid group '%s': %v", e.name, e.err)
+}
+
+// Set satisfies the flag.Value interface. The empty string leaves the
+// Group unset.
+func (g *Group) Set(s string) error {
+	if s == "" {
+		*g = Group{}
+		return nil
+	}
+	if id, ok := parseID(s); ok {
+		*g = Group{GID: id, set: true}
+		if grp, err := user.LookupGroupId(s); err == nil {
+			g.Name = grp.Name
+		}
+		return nil
+	}
+	grp, err := user.LookupGroup(s)
+	if err != nil {
+		return errGroupInvalid{s, err}
+	}
+	id, ok := parseID(grp.Gid)
+	if !ok {
+		return errGroupInvalid{s, fmt.Errorf("non-numeric gid '%s'", grp.Gid)}
+	}
+	*g = Group{Name: grp.Name, GID: id, set: true}
+	return nil
+}
+
+// String returns the Group's name, or its GID if it has no name.
+func (g Group) String() string {
+	switch {
+	case g.Name != "":
+		return g.Name
+	case g.set:
+		return strconv.Itoa(g.GID)
+	}
+	return ""
+}
+
+// IsSet returns true if the Group was assigned a value with Set or by
+// unmarshaling.
+func (g Group) IsSet() bool {
+	return g.set
+}
+
+// MarshalJSON satisfies the json.Marshaler interface
+func (g Group) MarshalJSON() ([]byte, error) {
+	return json.Marshal(g.String())
+}
+
+// MarshalYAML satisfies the yaml.Marshaler interface
+func (g Group) MarshalYAML() (interface{}, error) {
+	return g.String(), nil
+}
+
+// UnmarshalJSON satisfies the json.Unmarshaler interface, accepting a
+// name or a numeric ID as a string or number.
+func (g *Group) UnmarshalJSON(b []byte) error {
+	s, err := unmarshalID(b)
+	if err != nil {
+		return err
+	}
+	return g.Set(s)
+}
+
+// UnmarshalYAML satisfies the yaml.Unmarshaler interface
+func (g *Group) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var s string
+	if err := unmarshal(&s); err != nil {
+		return err
+	}
+	return g.Set(s)
+}
+
+// parseID parses a non-negative numeric user or group ID.
+func parseID(s string) (int, bool) {
+	id, err := strconv.Atoi(s)
+	return id, err == nil && id >= 0
+}
+
+func unmarshalID(b []byte) (string, error) {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		var n json.Number
+		if json.Unmarshal(b, &n) != nil {
+			return "", err
+		}
+		s = n.String()
+	}
+	return s, nil
+}
@@ -0,0 +1,75 @@
+/*
+ * Copyright 2018 Farsight Security, Inc.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package config
+
+import (
+	"encoding/json"
+	"os"
+	"os/user"
+	"strconv"
+	"testing"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+func TestFileMode(t *testing.T) {
+	var m FileMode
+	checkOK(t, nil, !m.IsSet() && m.String() == "")
+	checkOK(t, m.Set("0640"), m.IsSet() && m.FileMode == 0640)
+	checkOK(t, m.Set("0o755"), m.FileMode == 0755)
+	checkOK(t, m.Set("2775"), m.FileMode == 0775|os.ModeSetgid && m.String() == "2775")
+	checkOK(t, m.Set("rw-r-----"), m.FileMode == 0640)
+	checkOK(t, m.Set("rwxrwsr-x"), m.FileMode == 0775|os.ModeSetgid)
+	checkOK(t, m.Set("rwSr--r-T"), m.FileMode == 0644|os.ModeSetuid|os.ModeSticky && m.String() == "5644")
+	checkOK(t, m.Set("000"), m.IsSet() && m.String() == "0000")
+	checkOK(t, m.Set(""), !m.IsSet())
+
+	for _, s := range []string{"0800", "17777", "rw-r--r", "rw-r--r-x-", "rwxrwxrws", "abc", "-1"} {
+		checkErr(t, m.Set(s))
+	}
+
+	checkJSON(t, FileMode{FileMode: 0600, set: true}, `"0600"`)
+	checkYAML(t, FileMode{FileMode: 0777 | os.ModeSticky, set: true}, `"1777"`)
+	checkJSON(t, FileMode{}, `""`)
+
+	// An unquoted YAML octal number is read as written.
+	checkOK(t, yaml.Unmarshal([]byte(`0640`), &m), m.FileMode == 0640)
+}
+
+func TestUserGroup(t *testing.T) {
+	cur, err := user.Current()
+	if err != nil {
+		t.Skip(err)
+	}
+	uid, _ := strconv.Atoi(cur.Uid)
+
+	var u User
+	checkOK(t, u.Set(cur.Username), u.UID == uid && u.String() == cur.Username)
+	checkOK(t, u.Set(cur.Uid), u.UID == uid && u.Name == cur.Username)
+	checkOK(t, u.Set("987654"), u.UID == 987654 && u.String() == "987654")
+	checkOK(t, u.Set(""), !u.IsSet())
+	checkErr(t, u.Set("no-such-user-x"))
+	checkErr(t, u.Set("-1"))
+	checkOK(t, json.Unmarshal([]byte(cur.Uid), &u), u.UID == uid)
+	checkJSON(t, User{UID: 987654, set: true}, `"987654"`)
+	checkJSON(t, User{}, `""`)
+
+	grp, err := user.LookupGroupId(cur.Gid)
+	if err != nil {
+		t.Skip(err)
+	}
+	gid, _ := strconv.Atoi(cur.Gid)
+	var g Group
+	checkOK(t, g.Set(grp.Name), g.GID == gid && g.String() == grp.Name)
+	checkOK(t, g.Set(cur.Gid), g.Name == grp.Name)
+	checkOK(t, g.Set(""), !g.IsSet())
+	checkErr(t, g.Set("no-such-group-x"))
+	checkOK(t, yaml.Unmarshal([]byte(cur.Gid), &g), g.GID == gid)
+	checkYAML(t, Group{GID: 987654, set: true}, `"987654"`)
+}
@@ -0,0 +1,310 @@
+/*
+ * Copyright 2018 Farsight Security, Inc.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Rate provides JSON and YAML Marshaling and Unmarshaling for event rates.
+// The string format is a count, a slash, and an interval, where the interval
+// is either a unit name (s, min, h, or d, or their long forms such as
+// "second" or "minute") or a duration, e.g., "1000/s", "10/min", "5/100ms".
+type Rate struct {
+	Count float64
+	Per   time.Duration
+	set   bool
+}
+
+var rateUnits = []struct {
+	name    string
+	aliases []string
+	per     time.Duration
+}{
+	{"d", []string{"day", "days"}, 24 * time.Hour},
+	{"h", []string{"hr", "hour", "hours"}, time.Hour},
+	{"min", []string{"minute", "minutes"}, time.Minute},
+	{"s", []string{"sec", "second", "seconds"}, time.Second},
+}
+
+type errRateInvalid string
+
+func (e errRateInvalid) Error() string {
+	return fmt.Sprintf("Invalid rate '%s': should be count/interval",
+		string(e))
+}
+
+// Set satisfies the flag.Value interface for use as a command line
+// flag. The empty string leaves the Rate unset.
+func (r *Rate) Set(s string) error {
+	if strings.TrimSpace(s) == "" {
+		*r = Rate{}
+		return nil
+	}
+	l := strings.SplitN(s, "/", 2)
+	if len(l) < 2 {
+		return errRateInvalid(s)
+	}
+	count, err := strconv.ParseFloat(strings.TrimSpace(l[0]), 64)
+	if err != nil || !validCount(count) {
+		return errRateInvalid(s)
+	}
+	per, err := parseRateInterval(strings.TrimSpace(l[1]))
+	if err != nil || per <= 0 {
+		return errRateInvalid(s)
+	}
+	r.Count, r.Per, r.set = count, per, true
+	return nil
+}
+
+// validCount returns true if f is finite and not negative.
+func validCount(f float64) bool {
+	return !math.IsNaN(f) && !math.IsInf(f, 0) && f >= 0
+}
+
+func parseRateInterval(s string) (time.Duration, error) {
+	for _, u := range rateUnits {
+		if strings.EqualFold(s, u.name) {
+			return u.per, nil
+		}
+		for _, n := range u.aliases {
+			if strings.EqualFold(s, n) {
+				return u.per, nil
+			}
+		}
+	}
+	return time.ParseDuration(s)
+}
+
+// String returns the Rate in canonical form, using a unit name for the
+// interval where possible, e.g., "1000/s" or "5/100ms". An unset Rate
+// returns the empty string.
+func (r Rate) String() string {
+	if r.Per == 0 {
+		return ""
+	}
+	per := r.Per.String()
+	for _, u := range rateUnits {
+		if r.Per == u.per {
+			per = u.name
+			break
+		}
+	}
+	return strconv.FormatFloat(r.Count, 'f', -1, 64) + "/" + per
+}
+
+// IsSet returns true if the Rate was assigned a value with Set or by
+// unmarshaling.
+func (r Rate) IsSet() bool {
+	return r.set
+}
+
+// PerSecond returns the Rate in events per second, or zero if the Rate
+// is unset. The result may be converted directly to a rate.Limit from
+// golang.org/x/time/rate.
+func (r Rate) PerSecond() float64 {
+	if r.Per <= 0 {
+		return 0
+	}
+	return r.Count * float64(time.Second) / float64(r.Per)
+}
+
+// Limit is a synonym for PerSecond, e.g., rate.Limit(cfg.Rate.Limit()).
+func (r Rate) Limit() float64 {
+	return r.PerSecond()
+}
+
+// Every returns the interval between events at the Rate, or zero if the
+// Rate is zero.
+func (r Rate) Every() time.Duration {
+	if r.Count <= 0 {
+		return 0
+	}
+	return time.Duration(float64(r.Per) / r.Count)
+}
+
+// CountPer returns the number of events at the Rate in the interval d.
+func (r Rate) CountPer(d time.Duration) float64 {
+	if r.Per <= 0 {
+		return 0
+	}
+	return r.Count * float64(d) / float64(r.Per)
+}
+
+// MarshalJSON satisfies json.Marshaler
+func (r Rate) MarshalJSON() ([]byte, error) {
+	return json.Marshal(r.String())
+}
+
+// MarshalYAML satisfies yaml.Marshaler
+func (r Rate) MarshalYAML() (interface{}, error) {
+	return r.String(), nil
+}
+
+// UnmarshalJSON satisfies json.Unmarshaler
+func (r *Rate) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	return r.Set(s)
+}
+
+// UnmarshalYAML satisfies yaml.Unmarshaler
+func (r *Rate) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var s string
+	if err := unmarshal(&s); err != nil {
+		return err
+	}
+	return r.Set(s)
+}
+
+// Bandwidth provides JSON and YAML Marshaling and Unmarshaling for data
+// rates. The string format is a number followed by a unit of bits per second
+// (bps, kbps, Mbps, Gbps, Tbps; powers of 1000), bytes per second (Bps,
+// kBps, MBps, GBps, TBps), or a byte size as accepted by ByteSize per
+// second, e.g., "50Mbps", "1.5Gbps", "10MiB/s".
+type Bandwidth struct {
+	BitsPerSecond uint64
+	set           bool
+}
+
+var bandwidthUnits = []struct {
+	name string
+	bits uint64
+}{
+	{"Tbps", 1e12}, {"Gbps", 1e9}, {"Mbps", 1e6}, {"kbps", 1e3}, {"bps", 1},
+}
+
+type errBandwidthInvalid string
+
+func (e errBandwidthInvalid) Error() string {
+	return fmt.Sprintf("Invalid bandwidth '%s'", string(e))
+}
+
+// Set satisfies the flag.Value interface for use as a command line
+// flag. The empty string leaves the Bandwidth unset.
+func (bw *Bandwidth) Set(s string) error {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		*bw = Bandwidth{}
+		return nil
+	}
+	if strings.HasSuffix(s, "/s") {
+		b, err := ParseByteSize(strings.TrimSuffix(s, "/s"))
+		if err != nil || b > math.MaxUint64/8 {
+			return errBandwidthInvalid(s)
+		}
+		bw.BitsPerSecond, bw.set = b*8, true
+		return nil
+	}
+
+	var num string
+	var bits uint64
+	switch {
+	case strings.HasSuffix(s, "bps"):
+		num, bits = strings.TrimSuffix(s, "bps"), 1
+	case strings.HasSuffix(s, "Bps"):
+		num, bits = strings.TrimSuffix(s, "Bps"), 8
+	default:
+		return errBandwidthInvalid(s)
+	}
+	num = strings.TrimSpace(num)
+	if n := len(num); n > 0 {
+		switch num[n-1] {
+		case 'k', 'K':
+			num, bits = num[:n-1], bits*1e3
+		case 'M':
+			num, bits = num[:n-1], bits*1e6
+		case 'G':
+			num, bits = num[:n-1], bits*1e9
+		case 'T':
+			num, bits = num[:n-1], bits*1e12
+		}
+	}
+	f, err := strconv.ParseFloat(strings.TrimSpace(num), 64)
+	if err != nil || !validCount(f) || f*float64(bits) >= math.MaxUint64 {
+		return errBandwidthInvalid(s)
+	}
+	bw.BitsPerSecond, bw.set = uint64(f*float64(bits)), true
+	return nil
+}
+
+// String returns the Bandwidth in the largest unit of bits per second
+// which represents it exactly, e.g., "50Mbps". An unset Bandwidth returns
+// the empty string.
+func (bw Bandwidth) String() string {
+	if bw == (Bandwidth{}) {
+		return ""
+	}
+	for _, u := range bandwidthUnits {
+		if bw.BitsPerSecond%u.bits == 0 && bw.BitsPerSecond > 0 {
+			return strconv.FormatUint(bw.BitsPerSecond/u.bits, 10) + u.name
+		}
+	}
+	return "0bps"
+}
+
+// IsSet returns true if the Bandwidth was assigned a value with Set or by
+// unmarshaling.
+func (bw Bandwidth) IsSet() bool {
+	return bw.set
+}
+
+// BytesPerSecond returns the Bandwidth in bytes per second. The result may
+// be converted directly to a rate.Limit from golang.org/x/time/rate to
+// limit a byte stream.
+func (bw Bandwidth) BytesPerSecond() float64 {
+	return float64(bw.BitsPerSecond) / 8
+}
+
+// Limit is a synonym for BytesPerSecond, e.g.,
+// rate.Limit(cfg.Bandwidth.Limit()).
+func (bw Bandwidth) Limit() float64 {
+	return bw.BytesPerSecond()
+}
+
+// BytesPer returns the number of bytes transferred at the Bandwidth in the
+// interval d.
+func (bw Bandwidth) BytesPer(d time.Duration) uint64 {
+	return uint64(bw.BytesPerSecond() * d.Seconds())
+}
+
+// MarshalJSON satisfies json.Marshaler
+func (bw Bandwidth) MarshalJSON() ([]byte, error) {
+	return json.Marshal(bw.String())
+}
+
+// MarshalYAML satisfies yaml.Marshaler
+func (bw Bandwidth) MarshalYAML() (interface{}, error) {
+	return bw.String(), nil
+}
+
+// UnmarshalJSON satisfies json.Unmarshaler
+func (bw *Bandwidth) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	return bw.Set(s)
+}
+
+// UnmarshalYAML satisfies yaml.Unmarshaler
+func (bw *Bandwidth) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var s string
+	if err := unmarshal(&s); err != nil {
+		return err
+	}
+	return bw.Set(s)
+}
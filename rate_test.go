@@ -0,0 +1,70 @@
+/*
+ * Copyright 2018 Farsight Security, Inc.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package config
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+func TestRateSet(t *testing.T) {
+	var r Rate
+	checkOK(t, nil, !r.IsSet() && r.PerSecond() == 0 && r.Every() == 0)
+	checkOK(t, r.Set("1000/s"), r.IsSet() && r.Count == 1000 && r.Per == time.Second)
+	checkOK(t, nil, r.PerSecond() == 1000 && r.Every() == time.Millisecond)
+	checkOK(t, r.Set("10 / minute"), r.Per == time.Minute && r.String() == "10/min")
+	checkOK(t, r.Set("5/100ms"), r.PerSecond() == 50 && r.String() == "5/100ms")
+	checkOK(t, nil, r.CountPer(time.Second) == 50)
+	checkOK(t, r.Set("0.5/h"), r.String() == "0.5/h")
+
+	checkOK(t, r.Set(""), !r.IsSet() && r.String() == "")
+	for _, s := range []string{" /s", "10", "x/s", "10/x", "10/0s", "10/-1s",
+		"-1/s", "NaN/s", "Inf/s", "+Inf/s", "-Inf/s"} {
+		checkErr(t, r.Set(s))
+	}
+}
+
+func TestRateMarshal(t *testing.T) {
+	checkJSON(t, Rate{Count: 1000, Per: time.Second}, `"1000/s"`)
+	checkYAML(t, Rate{Count: 3, Per: 24 * time.Hour}, `3/d`)
+
+	checkJSON(t, Rate{}, `""`)
+	checkYAML(t, Rate{}, `""`)
+
+	var r Rate
+	checkErr(t, json.Unmarshal([]byte(`1000`), &r))
+	checkErr(t, yaml.Unmarshal([]byte(`NaN/s`), &r))
+}
+
+func TestBandwidthSet(t *testing.T) {
+	var bw Bandwidth
+	checkOK(t, nil, !bw.IsSet())
+	checkOK(t, bw.Set("50Mbps"), bw.IsSet() && bw.BitsPerSecond == 50e6)
+	checkOK(t, bw.Set("1.5Gbps"), bw.BitsPerSecond == 1.5e9 && bw.String() == "1500Mbps")
+	checkOK(t, bw.Set("1 kBps"), bw.BitsPerSecond == 8000 && bw.BytesPerSecond() == 1000)
+	checkOK(t, bw.Set("10MiB/s"), bw.BitsPerSecond == 80<<20 && bw.BytesPer(time.Second) == 10<<20)
+	checkOK(t, bw.Set("1EiB/s"), bw.BitsPerSecond == 8<<60)
+
+	checkOK(t, bw.Set(""), !bw.IsSet() && bw.String() == "")
+	checkOK(t, bw.Set("0bps"), bw.IsSet() && bw.String() == "0bps")
+	for _, s := range []string{"50", "50Xbps", "-1bps", "NaNbps", "Infbps",
+		"2EiB/s", "20000000Tbps", "1e30Gbps", "x/s"} {
+		checkErr(t, bw.Set(s))
+	}
+}
+
+func TestBandwidthMarshal(t *testing.T) {
+	checkJSON(t, Bandwidth{BitsPerSecond: 50e6}, `"50Mbps"`)
+	checkYAML(t, Bandwidth{BitsPerSecond: 1001}, `1001bps`)
+	checkJSON(t, Bandwidth{}, `""`)
+	checkYAML(t, Bandwidth{}, `""`)
+}
@@ -0,0 +1,233 @@
+/*
+ * Copyright 2018 Farsight Security, Inc.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// A Setter reports whether it was assigned a value. All of the value types
+// in this package satisfy Setter.
+type Setter interface {
+	IsSet() bool
+}
+
+// A zeroSetter is a Setter which cannot tell its zero value apart from an
+// absent setting, such as Duration, and so cannot be required.
+type zeroSetter interface {
+	zeroIsUnset()
+}
+
+var zeroSetterType = reflect.TypeOf((*zeroSetter)(nil)).Elem()
+
+type errNotRequirable struct{ path, typ string }
+
+func (e errNotRequirable) Error() string {
+	return fmt.Sprintf("Field %s of type %s cannot be required, use Optional[%s]",
+		e.path, e.typ, e.typ)
+}
+
+// MissingFieldsError lists the paths of required configuration fields which
+// were not set.
+type MissingFieldsError []string
+
+func (m MissingFieldsError) Error() string {
+	return fmt.Sprintf("Missing required configuration: %s",
+		strings.Join(m, ", "))
+}
+
+// Validate checks that all fields of the struct pointed to by i which are
+// tagged `config:"required"` have been set, descending into nested structs.
+// Fields satisfying Setter are checked with IsSet, other fields are
+// considered missing if they hold the zero value for their type. Types
+// whose zero value is a valid setting, such as Duration and TLSClientAuth,
+// cannot be required; Validate returns an error for such fields, which
+// should use Optional instead.
+//
+// If any required fields are missing, Validate returns a MissingFieldsError
+// listing all of them by their Go field path, e.g. "Server.Timeout".
+//
+// Validate also checks any FilePath and DirPath fields which were set by
+// unmarshaling, including elements of Lists, Maps and Optionals, and
+// applies the Policy of any CheckedURL fields, returning the first error
+// found.
+//
+// LoadYAML and LoadJSON do not check required fields, as values may still
+// be set later by command line flags. Call Validate once all sources of
+// configuration have been read.
+func Validate(i interface{}) error {
+	return validate(i, "", fieldName, true)
+}
+
+// validate checks the struct pointed to by i as described for Validate,
+// skipping required fields if required is false. Relative FilePaths and
+// DirPaths with RelativeToConfig set are resolved against dir, if not
+// empty.
+func validate(i interface{}, dir string, name func(reflect.StructField) string, required bool) error {
+	v := reflect.ValueOf(i)
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	vd := validator{dir: dir, name: name, required: required}
+	vd.validateValue(v, "")
+	if len(vd.missing) > 0 {
+		return vd.missing
+	}
+	return vd.err
+}
+
+// A loadChecker is checked after loading, with the directory of the config
+// file, if any.
+type loadChecker interface {
+	checkLoaded(dir string) error
+}
+
+// A container is a Setter holding values of other types, such as a List,
+// which are validated as its elements.
+type container interface {
+	elems() reflect.Value
+}
+
+type validator struct {
+	dir      string
+	name     func(reflect.StructField) string
+	required bool
+	missing  MissingFieldsError
+	err      error
+}
+
+func (vd *validator) validateValue(v reflect.Value, path string) {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if !v.IsNil() {
+			vd.validateValue(v.Elem(), path)
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			vd.validateValue(v.Index(i), fmt.Sprintf("%s[%d]", path, i))
+		}
+	case reflect.Map:
+		// Map values are not addressable, so each is checked as a copy,
+		// which is stored back in case checking changed it.
+		iter := v.MapRange()
+		for iter.Next() {
+			e := reflect.New(v.Type().Elem()).Elem()
+			e.Set(iter.Value())
+			vd.validateValue(e, fmt.Sprintf("%s[%v]", path, iter.Key()))
+			v.SetMapIndex(iter.Key(), e)
+		}
+	case reflect.Struct:
+		if v.CanAddr() && v.Addr().CanInterface() {
+			if c, ok := v.Addr().Interface().(loadChecker); ok {
+				if err := c.checkLoaded(vd.dir); err != nil && vd.err == nil {
+					vd.err = err
+					if path != "" {
+						vd.err = fmt.Errorf("%s: %w", path, err)
+					}
+				}
+			}
+		}
+		if _, ok := setter(v); ok {
+			if v.CanAddr() && v.Addr().CanInterface() {
+				if c, ok := v.Addr().Interface().(container); ok {
+					vd.validateValue(c.elems(), path)
+				}
+			}
+			return
+		}
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if f.PkgPath != "" && !f.Anonymous {
+				continue
+			}
+			fv := v.Field(i)
+			fpath := vd.name(f)
+			if f.Anonymous {
+				fpath = path
+			} else if path != "" {
+				fpath = path + "." + fpath
+			}
+			if vd.required && isRequired(f) {
+				if f.Type.Kind() == reflect.Struct && f.Type.Implements(zeroSetterType) {
+					if vd.err == nil {
+						vd.err = errNotRequirable{fpath, f.Type.Name()}
+					}
+					continue
+				}
+				if !isSet(fv) {
+					vd.missing = append(vd.missing, fpath)
+					continue
+				}
+			}
+			vd.validateValue(fv, fpath)
+		}
+	}
+}
+
+func isRequired(f reflect.StructField) bool {
+	for _, opt := range strings.Split(f.Tag.Get("config"), ",") {
+		if opt == "required" {
+			return true
+		}
+	}
+	return false
+}
+
+func isSet(v reflect.Value) bool {
+	if (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) && v.IsNil() {
+		return false
+	}
+	if v.Kind() == reflect.Ptr && v.Type().Implements(zeroSetterType) {
+		// A pointer records presence for a type which cannot.
+		return true
+	}
+	if s, ok := setter(v); ok {
+		return s.IsSet()
+	}
+	return !v.IsZero()
+}
+
+func setter(v reflect.Value) (Setter, bool) {
+	if v.CanAddr() && v.Addr().CanInterface() {
+		if s, ok := v.Addr().Interface().(Setter); ok {
+			return s, true
+		}
+	}
+	if v.CanInterface() {
+		if s, ok := v.Interface().(Setter); ok {
+			return s, true
+		}
+	}
+	return nil, false
+}
+
+func fieldName(f reflect.StructField) string {
+	return f.Name
+}
+
+// tagName returns a function naming fields by the given struct tag key,
+// falling back to the result of def when the tag is absent.
+func tagName(key string, def func(reflect.StructField) string) func(reflect.StructField) string {
+	return func(f reflect.StructField) string {
+		if n := strings.Split(f.Tag.Get(key), ",")[0]; n != "" && n != "-" {
+			return n
+		}
+		return def(f)
+	}
+}
+
+func yamlFieldName(f reflect.StructField) string {
+	return strings.ToLower(f.Name)
+}
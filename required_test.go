@@ -0,0 +1,89 @@
+/*
+ * Copyright 2018 Farsight Security, Inc.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package config
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+type requiredInner struct {
+	Label String `json:"label" config:"required"`
+	Name  string `json:"name"`
+}
+
+type requiredConfig struct {
+	Name    string         `json:"name" config:"required"`
+	Inner   requiredInner  `json:"inner"`
+	Ptr     *requiredInner `json:"ptr"`
+	List    []requiredInner
+	private int `config:"required"`
+}
+
+func TestValidate(t *testing.T) {
+	var c requiredConfig
+	err := Validate(&c)
+	m, ok := err.(MissingFieldsError)
+	checkOK(t, nil, ok)
+	if !reflect.DeepEqual(m, MissingFieldsError{"Name", "Inner.Label"}) {
+		t.Errorf("missing: got %v", m)
+	}
+
+	err = json.Unmarshal([]byte(`{"name":"x","inner":{"label":"a"},"ptr":{},"List":[{"label":"b"},{}]}`), &c)
+	checkOK(t, err, true)
+	m, ok = Validate(&c).(MissingFieldsError)
+	checkOK(t, nil, ok)
+	if !reflect.DeepEqual(m, MissingFieldsError{"Ptr.Label", "List[1].Label"}) {
+		t.Errorf("missing: got %v", m)
+	}
+
+	c.Ptr.Label.Set("c")
+	c.List[1].Label.Set("d")
+	checkOK(t, Validate(&c), true)
+	checkOK(t, Validate((*requiredConfig)(nil)), true)
+}
+
+func TestValidateTagName(t *testing.T) {
+	var c requiredConfig
+	err := validate(&c, "", tagName("json", fieldName), true)
+	if err == nil || err.Error() != "Missing required configuration: name, inner.label" {
+		t.Errorf("got %v", err)
+	}
+}
+
+func TestValidateZeroSetter(t *testing.T) {
+	var c struct {
+		Timeout  Duration      `config:"required"`
+		Auth     TLSClientAuth `config:"required"`
+		Interval *Duration     `config:"required"`
+	}
+	m, ok := Validate(&c).(MissingFieldsError)
+	checkOK(t, nil, ok && reflect.DeepEqual(m, MissingFieldsError{"Interval"}))
+
+	c.Interval = new(Duration)
+	err := Validate(&c)
+	if _, ok := err.(errNotRequirable); !ok {
+		t.Errorf("got %v, want errNotRequirable", err)
+	}
+}
+
+func TestLoadSkipsRequired(t *testing.T) {
+	var c requiredConfig
+	name := filepath.Join(t.TempDir(), "config.json")
+	checkOK(t, LoadJSON(&c, name, false), true)
+	if err := os.WriteFile(name, []byte(`{"inner":{}}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	checkOK(t, LoadJSON(&c, name, true), true)
+	_, ok := Validate(&c).(MissingFieldsError)
+	checkOK(t, nil, ok)
+}
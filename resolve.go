@@ -0,0 +1,380 @@
+/*
+ * Copyright 2018 Farsight Security, Inc.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// hostAddr holds a TCP or UDP address in the host:port form it was given,
+// along with the cached results of resolving it.
+type hostAddr struct {
+	network, address string
+	host             string
+	port             int
+
+	mu      sync.Mutex
+	ips     []net.IPAddr
+	expires time.Time
+}
+
+func newHostAddr(network, address string) (*hostAddr, error) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+	p, err := net.LookupPort(network, port)
+	if err != nil {
+		return nil, err
+	}
+	return &hostAddr{network: network, address: address, host: host, port: p}, nil
+}
+
+// literal returns the address's IP if the host is an IP literal or empty,
+// and false otherwise.
+func (h *hostAddr) literal() (net.IPAddr, bool) {
+	if h.host == "" {
+		return net.IPAddr{}, true
+	}
+	ip, zone := h.host, ""
+	if i := strings.LastIndex(ip, "%"); i > 0 {
+		ip, zone = ip[:i], ip[i+1:]
+	}
+	if a := net.ParseIP(ip); a != nil {
+		return net.IPAddr{IP: a, Zone: zone}, true
+	}
+	return net.IPAddr{}, false
+}
+
+// resolve looks up the address's host, returning all of its addresses
+// matching the network. Results are cached, and looked up again once
+// refresh has elapsed if refresh is nonzero. If a repeated lookup fails,
+// the previous results are returned. The lookup is made without holding
+// the lock, so concurrent callers may look up the host at the same time.
+func (h *hostAddr) resolve(ctx context.Context, refresh time.Duration) ([]net.IPAddr, error) {
+	if a, ok := h.literal(); ok {
+		return []net.IPAddr{a}, nil
+	}
+
+	h.mu.Lock()
+	prev := h.ips
+	if prev != nil && (refresh == 0 || time.Now().Before(h.expires)) {
+		h.mu.Unlock()
+		return prev, nil
+	}
+	h.mu.Unlock()
+
+	all, err := net.DefaultResolver.LookupIPAddr(ctx, h.host)
+	var ips []net.IPAddr
+	for _, a := range all {
+		switch {
+		case strings.HasSuffix(h.network, "4") && a.IP.To4() == nil:
+		case strings.HasSuffix(h.network, "6") && a.IP.To4() != nil:
+		default:
+			ips = append(ips, a)
+		}
+	}
+	if err == nil && len(ips) == 0 {
+		err = &net.DNSError{Err: "no suitable address found", Name: h.host}
+	}
+	if err != nil {
+		if prev != nil {
+			return prev, nil
+		}
+		return nil, err
+	}
+	h.mu.Lock()
+	h.ips, h.expires = ips, time.Now().Add(refresh)
+	h.mu.Unlock()
+	return ips, nil
+}
+
+// TCPHost is a TCP address whose host name is resolved when it is used,
+// rather than when it is set as for TCPAddr. ResolveAll, Resolve, Dial and
+// Listen look up the host name, caching the results, and look it up again
+// after Refresh if it is nonzero.
+//
+// The network may be omitted, defaulting to "tcp", and if DefaultPort is
+// set before Set or unmarshaling, so may the port, e.g.:
+//
+//      cfg := Config{
+//              Upstream: config.TCPHost{DefaultPort: "443", Refresh: time.Minute},
+//      }
+//
+// See Addr for the accepted address forms. A TCPHost marshals to the
+// address as given, and an unset TCPHost to the empty string.
+type TCPHost struct {
+	DefaultPort string
+	Refresh     time.Duration
+	host        *hostAddr
+}
+
+// Set satisfies flag.Value for use in command line arguments. The empty
+// string leaves the TCPHost unset.
+func (t *TCPHost) Set(s string) error {
+	h, err := setHost(s, "tcp", t.DefaultPort)
+	if err != nil {
+		return err
+	}
+	t.host = h
+	return nil
+}
+
+// setHost parses s for a TCPHost or UDPHost in the network defNet,
+// returning nil if s is empty.
+func setHost(s, defNet, defPort string) (*hostAddr, error) {
+	if s == "" {
+		return nil, nil
+	}
+	a, err := parseAddr(s, defNet, defPort)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case defNet == "tcp" && !isTCPNetwork(a.Net):
+		return nil, errInvalidTCPNetwork(a.Net)
+	case defNet == "udp" && !isUDPNetwork(a.Net):
+		return nil, errInvalidUDPNetwork(a.Net)
+	}
+	return newHostAddr(a.Net, a.Addr)
+}
+
+// IsSet returns true if the TCPHost was assigned a value with Set or by
+// unmarshaling.
+func (t TCPHost) IsSet() bool {
+	return t.host != nil
+}
+
+// Network returns the TCPHost's network, e.g., "tcp" or "tcp6".
+func (t TCPHost) Network() string {
+	if t.host == nil {
+		return "tcp"
+	}
+	return t.host.network
+}
+
+// Address returns the TCPHost's host:port as given to Set or in the
+// configuration.
+func (t TCPHost) Address() string {
+	if t.host == nil {
+		return ""
+	}
+	return t.host.address
+}
+
+// String returns the TCPHost's address as given, with its network.
+func (t TCPHost) String() string {
+	if t.host == nil {
+		return ""
+	}
+	return t.host.network + ":" + t.host.address
+}
+
+// ResolveAll returns all addresses of the TCPHost's host name, looking
+// them up if they have not been resolved within the Refresh interval.
+func (t TCPHost) ResolveAll(ctx context.Context) ([]*net.TCPAddr, error) {
+	if t.host == nil {
+		return nil, errAddrNotSet{}
+	}
+	ips, err := t.host.resolve(ctx, t.Refresh)
+	if err != nil {
+		return nil, err
+	}
+	addrs := make([]*net.TCPAddr, len(ips))
+	for i, ip := range ips {
+		addrs[i] = &net.TCPAddr{IP: ip.IP, Port: t.host.port, Zone: ip.Zone}
+	}
+	return addrs, nil
+}
+
+// Resolve returns the first of the TCPHost's addresses found by
+// ResolveAll.
+func (t TCPHost) Resolve() (*net.TCPAddr, error) {
+	addrs, err := t.ResolveAll(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	return addrs[0], nil
+}
+
+// DialContext connects to the TCPHost, trying each of its addresses in
+// turn until one succeeds.
+func (t TCPHost) DialContext(ctx context.Context) (net.Conn, error) {
+	addrs, err := t.ResolveAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var d net.Dialer
+	for _, a := range addrs {
+		var c net.Conn
+		if c, err = d.DialContext(ctx, t.Network(), a.String()); err == nil {
+			return c, nil
+		}
+	}
+	return nil, err
+}
+
+// Dial connects to the TCPHost as with DialContext.
+func (t TCPHost) Dial() (net.Conn, error) {
+	return t.DialContext(context.Background())
+}
+
+// UnmarshalJSON satisfies the json.Unmarshaler interface
+func (t *TCPHost) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	return t.Set(s)
+}
+
+// UnmarshalYAML satisfies the yaml.Unmarshaler interface
+func (t *TCPHost) UnmarshalYAML(u func(interface{}) error) error {
+	var s string
+	if err := u(&s); err != nil {
+		return err
+	}
+	return t.Set(s)
+}
+
+// MarshalJSON satisfies the json.Marshaler interface
+func (t TCPHost) MarshalJSON() ([]byte, error) {
+	return json.Marshal(t.String())
+}
+
+// MarshalYAML satisfies the yaml.Marshaler interface
+func (t TCPHost) MarshalYAML() (interface{}, error) {
+	return t.String(), nil
+}
+
+// UDPHost is a UDP address whose host name is resolved when it is used,
+// as described for TCPHost. The network may be omitted, defaulting to
+// "udp".
+type UDPHost struct {
+	DefaultPort string
+	Refresh     time.Duration
+	host        *hostAddr
+}
+
+// Set satisfies flag.Value for use in command line arguments. The empty
+// string leaves the UDPHost unset.
+func (u *UDPHost) Set(s string) error {
+	h, err := setHost(s, "udp", u.DefaultPort)
+	if err != nil {
+		return err
+	}
+	u.host = h
+	return nil
+}
+
+// IsSet returns true if the UDPHost was assigned a value with Set or by
+// unmarshaling.
+func (u UDPHost) IsSet() bool {
+	return u.host != nil
+}
+
+// Network returns the UDPHost's network, e.g., "udp" or "udp6".
+func (u UDPHost) Network() string {
+	if u.host == nil {
+		return "udp"
+	}
+	return u.host.network
+}
+
+// Address returns the UDPHost's host:port as given to Set or in the
+// configuration.
+func (u UDPHost) Address() string {
+	if u.host == nil {
+		return ""
+	}
+	return u.host.address
+}
+
+// String returns the UDPHost's address as given, with its network.
+func (u UDPHost) String() string {
+	if u.host == nil {
+		return ""
+	}
+	return u.host.network + ":" + u.host.address
+}
+
+// ResolveAll returns all addresses of the UDPHost's host name, looking
+// them up if they have not been resolved within the Refresh interval.
+func (u UDPHost) ResolveAll(ctx context.Context) ([]*net.UDPAddr, error) {
+	if u.host == nil {
+		return nil, errAddrNotSet{}
+	}
+	ips, err := u.host.resolve(ctx, u.Refresh)
+	if err != nil {
+		return nil, err
+	}
+	addrs := make([]*net.UDPAddr, len(ips))
+	for i, ip := range ips {
+		addrs[i] = &net.UDPAddr{IP: ip.IP, Port: u.host.port, Zone: ip.Zone}
+	}
+	return addrs, nil
+}
+
+// Resolve returns the first of the UDPHost's addresses found by
+// ResolveAll.
+func (u UDPHost) Resolve() (*net.UDPAddr, error) {
+	addrs, err := u.ResolveAll(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	return addrs[0], nil
+}
+
+// DialContext connects to the first of the UDPHost's addresses.
+func (u UDPHost) DialContext(ctx context.Context) (net.Conn, error) {
+	addrs, err := u.ResolveAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var d net.Dialer
+	return d.DialContext(ctx, u.Network(), addrs[0].String())
+}
+
+// Dial connects to the UDPHost as with DialContext.
+func (u UDPHost) Dial() (net.Conn, error) {
+	return u.DialContext(context.Background())
+}
+
+// UnmarshalJSON satisfies the json.Unmarshaler interface
+func (u *UDPHost) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	return u.Set(s)
+}
+
+// UnmarshalYAML satisfies the yaml.Unmarshaler interface
+func (u *UDPHost) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var s string
+	if err := unmarshal(&s); err != nil {
+		return err
+	}
+	return u.Set(s)
+}
+
+// MarshalJSON satisfies the json.Marshaler interface
+func (u UDPHost) MarshalJSON() ([]byte, error) {
+	return json.Marshal(u.String())
+}
+
+// MarshalYAML satisfies the yaml.Marshaler interface
+func (u UDPHost) MarshalYAML() (interface{}, error) {
+	return u.String(), nil
+}
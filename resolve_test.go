@@ -0,0 +1,138 @@
+/*
+ * Copyright 2018 Farsight Security, Inc.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"testing"
+	"time"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+func TestTCPAddr(t *testing.T) {
+	var a TCPAddr
+	checkOK(t, nil, !a.IsSet())
+	checkOK(t, a.Set("192.0.2.1:80"), a.IsSet() && a.Port == 80 && a.IP.String() == "192.0.2.1")
+	checkOK(t, a.Set("tcp6:[2001:db8::1]:53"), a.Port == 53)
+	checkOK(t, a.Set(""), !a.IsSet())
+	checkErr(t, a.Set("udp:192.0.2.1:80"))
+	checkErr(t, a.Set("192.0.2.1"))
+
+	checkJSON(t, TCPAddr{}, `""`)
+	checkYAML(t, TCPAddr{}, `""`)
+	checkJSON(t, TCPAddr{&net.TCPAddr{IP: net.IPv4(192, 0, 2, 1), Port: 80}}, `"tcp:192.0.2.1:80"`)
+
+	// A TCPAddr marshals as it was given until it is changed.
+	checkOK(t, a.Set("tcp://192.0.2.1:http"), a.Port == 80)
+	checkJSON(t, a, `"tcp://192.0.2.1:http"`)
+	a.Port = 81
+	checkJSON(t, a, `"tcp:192.0.2.1:81"`)
+
+	var c struct{ A TCPAddr }
+	checkOK(t, json.Unmarshal([]byte(`{"A":""}`), &c), !c.A.IsSet())
+	checkOK(t, yaml.Unmarshal([]byte(`a: 127.0.0.1:8080`), &c), c.A.Port == 8080)
+
+	l, err := TCPAddr{&net.TCPAddr{IP: net.IPv4(127, 0, 0, 1)}}.Listen()
+	checkOK(t, err, l != nil)
+	l.Close()
+	_, err = TCPAddr{}.Listen()
+	checkErr(t, err)
+}
+
+func TestUDPAddr(t *testing.T) {
+	var a UDPAddr
+	checkOK(t, a.Set("udp4:192.0.2.1:53"), a.IsSet() && a.Port == 53)
+	checkOK(t, a.Set(""), !a.IsSet())
+	checkErr(t, a.Set("tcp:192.0.2.1:53"))
+	checkJSON(t, UDPAddr{}, `""`)
+	checkYAML(t, UDPAddr{&net.UDPAddr{IP: net.IPv4(192, 0, 2, 1), Port: 53}}, `udp:192.0.2.1:53`)
+	checkOK(t, a.Set("192.0.2.1:53"), a.IsSet())
+	checkYAML(t, a, `192.0.2.1:53`)
+
+	c, err := UDPAddr{&net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)}}.ListenPacket()
+	checkOK(t, err, c != nil)
+	c.Close()
+}
+
+func TestTCPHost(t *testing.T) {
+	h := TCPHost{DefaultPort: "443"}
+	checkOK(t, nil, !h.IsSet() && h.String() == "" && h.Network() == "tcp")
+	checkOK(t, h.Set("example.com"), h.IsSet() && h.Address() == "example.com:443")
+	checkOK(t, nil, h.String() == "tcp:example.com:443")
+	checkOK(t, h.Set("tcp6:[2001:db8::1]"), h.Network() == "tcp6")
+	a, err := h.Resolve()
+	checkOK(t, err, a.Port == 443 && a.IP.String() == "2001:db8::1")
+	checkOK(t, h.Set(""), !h.IsSet())
+	checkErr(t, h.Set("udp:example.com:53"))
+	checkErr(t, h.Set("example.com:nosuchservice"))
+	_, err = h.Resolve()
+	checkErr(t, err)
+
+	checkJSON(t, TCPHost{}, `""`)
+	h.Set("example.com")
+	checkJSON(t, h, `"tcp:example.com:443"`)
+	checkYAML(t, h, `tcp:example.com:443`)
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	checkOK(t, h.Set(l.Addr().String()), true)
+	c, err := h.Dial()
+	checkOK(t, err, c != nil)
+	c.Close()
+}
+
+func TestTCPHostRefresh(t *testing.T) {
+	var h TCPHost
+	checkOK(t, h.Set("example.invalid:80"), true)
+	cached := []net.IPAddr{{IP: net.IPv4(192, 0, 2, 1)}}
+	h.host.ips, h.host.expires = cached, time.Now().Add(time.Hour)
+	h.Refresh = time.Minute
+	addrs, err := h.ResolveAll(context.Background())
+	checkOK(t, err, len(addrs) == 1 && addrs[0].String() == "192.0.2.1:80")
+}
+
+func TestUDPHost(t *testing.T) {
+	h := UDPHost{DefaultPort: "53"}
+	checkOK(t, h.Set("[::1]"), h.Address() == "[::1]:53" && h.Network() == "udp")
+	a, err := h.Resolve()
+	checkOK(t, err, a.Port == 53)
+	checkErr(t, h.Set("tcp:[::1]:53"))
+	checkOK(t, h.Set("udp4:127.0.0.1:0"), true)
+	c, err := h.ListenPacket()
+	checkOK(t, err, c != nil)
+	c.Close()
+	checkJSON(t, UDPHost{}, `""`)
+}
+
+func TestHostListElem(t *testing.T) {
+	l := List[TCPHost]{Elem: TCPHost{DefaultPort: "53"}}
+	checkOK(t, l.Set("192.0.2.1,192.0.2.2:5353"), l.Len() == 2)
+	checkOK(t, nil, l.Values[0].Address() == "192.0.2.1:53" && l.Values[1].Address() == "192.0.2.2:5353")
+
+	l = List[TCPHost]{Elem: TCPHost{DefaultPort: "53"}}
+	checkOK(t, json.Unmarshal([]byte(`["a.example", "b.example:54"]`), &l),
+		l.Values[0].Address() == "a.example:53" && l.Values[1].Address() == "b.example:54")
+
+	var c struct{ Upstreams List[TCPHost] }
+	c.Upstreams.Elem.DefaultPort = "853"
+	checkOK(t, yaml.Unmarshal([]byte("upstreams: [a.example, ~]"), &c),
+		c.Upstreams.Values[0].Address() == "a.example:853" && !c.Upstreams.Values[1].IsSet())
+
+	m := Map[string, UDPHost]{Elem: UDPHost{DefaultPort: "514"}}
+	checkOK(t, m.Set("a=192.0.2.1"), m.Values["a"].Address() == "192.0.2.1:514")
+	checkOK(t, json.Unmarshal([]byte(`{"b":"192.0.2.2"}`), &m), m.Values["b"].Address() == "192.0.2.2:514")
+	checkOK(t, yaml.Unmarshal([]byte(`c: 192.0.2.3`), &m), m.Values["c"].Address() == "192.0.2.3:514")
+	checkErr(t, yaml.Unmarshal([]byte(`c: tcp:192.0.2.3:1`), &m))
+}
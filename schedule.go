@@ -0,0 +1,272 @@
+/*
+ * Copyright 2018 Farsight Security, Inc.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule is a recurring schedule, given as a cron expression or as an
+// interval.
+//
+// A cron expression has the five fields minute (0-59), hour (0-23), day
+// of month (1-31), month (1-12 or jan-dec), and day of week (0-7 or
+// sun-sat, with both 0 and 7 being Sunday), e.g., "30 2 * * mon-fri".
+// Each field is "*" or a comma-separated list of values and ranges
+// ("1-5"), optionally with a step ("*/15", "0-30/10"). As in cron, if both
+// the day of month and day of week are restricted, i.e., do not begin with
+// "*", a day matching either matches. The shorthands "@yearly" (or "@annually"), "@monthly",
+// "@weekly", "@daily" (or "@midnight"), and "@hourly" are accepted.
+//
+// An interval is written "@every" followed by a Duration, e.g.,
+// "@every 5m".
+type Schedule struct {
+	spec  string
+	every time.Duration
+	cron  *cronSpec
+}
+
+type cronSpec struct {
+	minute, hour, dom, month, dow uint64
+	domAny, dowAny                bool
+}
+
+var cronShorthands = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+var cronMonths = map[string]int{
+	"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+	"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+}
+
+var cronDays = map[string]int{
+	"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+}
+
+type errScheduleInvalid struct{ spec, reason string }
+
+func (e errScheduleInvalid) Error() string {
+	return fmt.Sprintf("Invalid schedule '%s': %s", e.spec, e.reason)
+}
+
+// Set satisfies the flag.Value interface. The empty string leaves the
+// Schedule unset.
+func (s *Schedule) Set(spec string) error {
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		*s = Schedule{}
+		return nil
+	}
+	if strings.HasPrefix(spec, "@every ") {
+		var d Duration
+		if err := d.Set(strings.TrimSpace(spec[len("@every "):])); err != nil {
+			return errScheduleInvalid{spec, err.Error()}
+		}
+		if d.Duration <= 0 {
+			return errScheduleInvalid{spec, "interval must be positive"}
+		}
+		*s = Schedule{spec: spec, every: d.Duration}
+		return nil
+	}
+	expr := spec
+	if strings.HasPrefix(spec, "@") {
+		var ok bool
+		if expr, ok = cronShorthands[strings.ToLower(spec)]; !ok {
+			return errScheduleInvalid{spec, "unknown shorthand"}
+		}
+	}
+	c, err := parseCron(expr)
+	if err != nil {
+		return errScheduleInvalid{spec, err.Error()}
+	}
+	*s = Schedule{spec: spec, cron: c}
+	return nil
+}
+
+func parseCron(expr string) (*cronSpec, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("expected 5 fields, found %d", len(fields))
+	}
+	var c cronSpec
+	var err error
+	if c.minute, err = parseCronField(fields[0], 0, 59, nil); err != nil {
+		return nil, err
+	}
+	if c.hour, err = parseCronField(fields[1], 0, 23, nil); err != nil {
+		return nil, err
+	}
+	if c.dom, err = parseCronField(fields[2], 1, 31, nil); err != nil {
+		return nil, err
+	}
+	if c.month, err = parseCronField(fields[3], 1, 12, cronMonths); err != nil {
+		return nil, err
+	}
+	if c.dow, err = parseCronField(fields[4], 0, 7, cronDays); err != nil {
+		return nil, err
+	}
+	if c.dow&(1<<7) != 0 {
+		c.dow |= 1
+	}
+	c.domAny = strings.HasPrefix(fields[2], "*")
+	c.dowAny = strings.HasPrefix(fields[4], "*")
+	return &c, nil
+}
+
+// parseCronField returns the set of values matched by a cron field as a
+// bit mask.
+func parseCronField(field string, min, max int, names map[string]int) (uint64, error) {
+	var bits uint64
+	for _, item := range strings.Split(field, ",") {
+		rng, step := item, 1
+		if i := strings.Index(item, "/"); i >= 0 {
+			n, err := strconv.Atoi(item[i+1:])
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("invalid step in '%s'", item)
+			}
+			rng, step = item[:i], n
+		}
+		lo, hi := min, max
+		if rng != "*" {
+			var err error
+			bounds := strings.SplitN(rng, "-", 2)
+			if lo, err = cronValue(bounds[0], min, max, names); err != nil {
+				return 0, err
+			}
+			hi = lo
+			if len(bounds) == 2 {
+				if hi, err = cronValue(bounds[1], min, max, names); err != nil {
+					return 0, err
+				}
+			} else if step > 1 {
+				hi = max
+			}
+			if hi < lo {
+				return 0, fmt.Errorf("invalid range '%s'", rng)
+			}
+		}
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+func cronValue(s string, min, max int, names map[string]int) (int, error) {
+	if v, ok := names[strings.ToLower(s)]; ok {
+		return v, nil
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil || v < min || v > max {
+		return 0, fmt.Errorf("invalid value '%s'", s)
+	}
+	return v, nil
+}
+
+func (c *cronSpec) dayMatches(t time.Time) bool {
+	dom := c.dom&(1<<uint(t.Day())) != 0
+	dow := c.dow&(1<<uint(t.Weekday())) != 0
+	switch {
+	case c.domAny && c.dowAny:
+		return true
+	case c.domAny:
+		return dow
+	case c.dowAny:
+		return dom
+	}
+	return dom || dow
+}
+
+// next returns the first time after t matching the cron expression, or
+// the zero time if there is none within five years.
+func (c *cronSpec) next(t time.Time) time.Time {
+	loc := t.Location()
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		y, m, d := t.Date()
+		switch {
+		case c.month&(1<<uint(m)) == 0:
+			t = time.Date(y, m+1, 1, 0, 0, 0, 0, loc)
+		case !c.dayMatches(t):
+			t = time.Date(y, m, d+1, 0, 0, 0, 0, loc)
+		case c.hour&(1<<uint(t.Hour())) == 0:
+			t = time.Date(y, m, d, t.Hour()+1, 0, 0, 0, loc)
+		case c.minute&(1<<uint(t.Minute())) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// Next returns the first time after t in the schedule, in t's location.
+// For an "@every" schedule, this is t plus the interval. Next returns the
+// zero time if the Schedule is not set, or if no time within five years
+// after t matches its cron expression, e.g., for "0 0 31 2 *".
+func (s Schedule) Next(t time.Time) time.Time {
+	switch {
+	case s.every > 0:
+		return t.Add(s.every)
+	case s.cron != nil:
+		return s.cron.next(t)
+	}
+	return time.Time{}
+}
+
+// String satisfies the flag.Value interface.
+func (s Schedule) String() string {
+	return s.spec
+}
+
+// IsSet returns true if the Schedule was assigned a value with Set or by
+// unmarshaling.
+func (s Schedule) IsSet() bool {
+	return s.spec != ""
+}
+
+// MarshalJSON satisfies the json.Marshaler interface
+func (s Schedule) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.spec)
+}
+
+// MarshalYAML satisfies the yaml.Marshaler interface
+func (s Schedule) MarshalYAML() (interface{}, error) {
+	return s.spec, nil
+}
+
+// UnmarshalJSON satisfies the json.Unmarshaler interface
+func (s *Schedule) UnmarshalJSON(b []byte) error {
+	var v string
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	return s.Set(v)
+}
+
+// UnmarshalYAML satisfies the yaml.Unmarshaler interface
+func (s *Schedule) UnmarshalYAML(u func(interface{}) error) error {
+	var v string
+	if err := u(&v); err != nil {
+		return err
+	}
+	return s.Set(v)
+}
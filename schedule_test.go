@@ -0,0 +1,58 @@
+/*
+ * Copyright 2018 Farsight Security, Inc.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSchedule(t *testing.T) {
+	// 2018-06-01 is a Friday.
+	start := time.Date(2018, 6, 1, 12, 0, 30, 0, time.UTC)
+	at := func(d, h, m int) time.Time {
+		return time.Date(2018, 6, d, h, m, 0, 0, time.UTC)
+	}
+	for _, c := range []struct {
+		spec string
+		next time.Time
+	}{
+		{"@every 90m", start.Add(90 * time.Minute)},
+		{"*/15 * * * *", at(1, 12, 15)},
+		{"30 2 * * mon-fri", at(4, 2, 30)},
+		{"0 0 * * 7", at(3, 0, 0)},
+		{"0 0 1 * *", time.Date(2018, 7, 1, 0, 0, 0, 0, time.UTC)},
+		{"@daily", at(2, 0, 0)},
+		{"0 9 1-7 jun *", at(2, 9, 0)},
+		{"0 0 15 * sat", at(2, 0, 0)},
+		// A day of week beginning with "*" does not restrict the day.
+		{"0 0 15 * */2", at(15, 0, 0)},
+		{"0 0 */10 * sat", at(2, 0, 0)},
+	} {
+		var s Schedule
+		if err := s.Set(c.spec); err != nil {
+			t.Errorf("%s: %v", c.spec, err)
+			continue
+		}
+		if n := s.Next(start); !n.Equal(c.next) {
+			t.Errorf("%s: next is %v, want %v", c.spec, n, c.next)
+		}
+	}
+
+	var s Schedule
+	checkOK(t, nil, !s.IsSet() && s.Next(start).IsZero())
+	checkOK(t, s.Set("0 0 31 2 *"), s.Next(start).IsZero())
+	for _, spec := range []string{"* * * *", "60 * * * *", "* * 0 * *",
+		"*/0 * * * *", "5-1 * * * *", "@weekdays", "@every 0s", "@every x", "* * * foo *"} {
+		checkErr(t, s.Set(spec))
+	}
+	checkJSON(t, Schedule{}, `""`)
+	checkOK(t, s.Set("30 2 * * MON-FRI"), true)
+	checkJSON(t, s, `"30 2 * * MON-FRI"`)
+}
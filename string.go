@@ -27,6 +27,7 @@ import (
 // if applicable) in all cases.
 type String struct {
 	source, value string
+	set           bool
 }
 
 // String() returns the string value of the string.
@@ -40,7 +41,7 @@ func (s *String) Set(v string) (err error) {
 	s.source = v
 	if strings.HasPrefix(v, "$") {
 		s.value = os.Getenv(v[1:])
-	} else if strings.HasPrefix(v, "/") || strings.HasPrefix(v, "./") || strings.HasPrefix(v, "../") {
+	} else if isFileRef(v) {
 		buf, err := ioutil.ReadFile(v)
 		if err != nil {
 			return err
@@ -49,9 +50,26 @@ func (s *String) Set(v string) (err error) {
 	} else {
 		s.value = v
 	}
+	s.set = true
 	return nil
 }
 
+func isFileRef(v string) bool {
+	return strings.HasPrefix(v, "/") || strings.HasPrefix(v, "./") || strings.HasPrefix(v, "../")
+}
+
+// literal returns true if the String was set to a literal value rather
+// than an environment variable or file reference.
+func (s *String) literal() bool {
+	return s.source != "" && !strings.HasPrefix(s.source, "$") && !isFileRef(s.source)
+}
+
+// IsSet returns true if the String was assigned a value with Set or by
+// unmarshaling.
+func (s *String) IsSet() bool {
+	return s.set
+}
+
 // UnmarshalJSON satisfies the json.Unmarshaler interface
 func (s *String) UnmarshalJSON(b []byte) error {
 	var v string
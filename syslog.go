@@ -0,0 +1,139 @@
+/*
+ * Copyright 2018 Farsight Security, Inc.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Syslog facility and severities, as defined in RFC 5424.
+const (
+	syslogDaemon  = 3
+	syslogErr     = 3
+	syslogWarning = 4
+	syslogInfo    = 6
+	syslogDebug   = 7
+)
+
+// syslogPaths are the usual locations of the local syslog socket.
+var syslogPaths = []string{"/dev/log", "/var/run/syslog", "/var/run/log"}
+
+// syslogWriter sends each write as a syslog message with the current
+// severity. Writes from a syslogHandler are serialized by handleMu, which
+// is held while the severity is set and the record is written.
+type syslogWriter struct {
+	handleMu sync.Mutex
+	severity int
+
+	mu       sync.Mutex
+	addr     Addr
+	conn     net.Conn
+	local    bool
+	tag      string
+	hostname string
+}
+
+func newSyslogWriter(a Addr) (*syslogWriter, error) {
+	w := &syslogWriter{
+		addr:     a,
+		local:    a.Addr == nil,
+		severity: syslogInfo,
+		tag:      filepath.Base(os.Args[0]),
+	}
+	w.hostname, _ = os.Hostname()
+	if err := w.connect(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+func (w *syslogWriter) connect() (err error) {
+	if !w.local {
+		w.conn, err = net.Dial(w.addr.Network(), w.addr.String())
+		return err
+	}
+	for _, path := range syslogPaths {
+		for _, network := range []string{"unixgram", "unix"} {
+			if w.conn, err = net.Dial(network, path); err == nil {
+				return nil
+			}
+		}
+	}
+	return errors.New("Unable to connect to local syslog")
+}
+
+func syslogSeverity(level slog.Level) int {
+	switch {
+	case level >= slog.LevelError:
+		return syslogErr
+	case level >= slog.LevelWarn:
+		return syslogWarning
+	case level >= slog.LevelInfo:
+		return syslogInfo
+	}
+	return syslogDebug
+}
+
+// Write sends p as a single message, reconnecting once if the send fails.
+func (w *syslogWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	msg := w.format(bytes.TrimRight(p, "\n"))
+	if w.conn != nil {
+		if _, err := w.conn.Write(msg); err == nil {
+			return len(p), nil
+		}
+		w.conn.Close()
+		w.conn = nil
+	}
+	if err := w.connect(); err != nil {
+		return 0, err
+	}
+	if _, err := w.conn.Write(msg); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (w *syslogWriter) format(p []byte) []byte {
+	pri := syslogDaemon*8 + w.severity
+	var b bytes.Buffer
+	if w.local {
+		fmt.Fprintf(&b, "<%d>%s %s[%d]: %s",
+			pri, time.Now().Format(time.Stamp), w.tag, os.Getpid(), p)
+	} else {
+		fmt.Fprintf(&b, "<%d>%s %s %s[%d]: %s",
+			pri, time.Now().Format(time.RFC3339), w.hostname, w.tag,
+			os.Getpid(), p)
+	}
+	if w.local || !strings.HasPrefix(w.addr.Network(), "udp") {
+		b.WriteByte('\n')
+	}
+	return b.Bytes()
+}
+
+func (w *syslogWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.conn == nil {
+		return nil
+	}
+	err := w.conn.Close()
+	w.conn = nil
+	return err
+}
@@ -0,0 +1,278 @@
+/*
+ * Copyright 2018 Farsight Security, Inc.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Time provides JSON, YAML and flag support for time.Time values. Times
+// are written in RFC 3339 format, e.g., "2018-06-01T12:00:00Z", unless
+// Layout is set before Set or unmarshaling, in which case they are parsed
+// and formatted with the given time.Parse layout, e.g.:
+//
+//      cfg := Config{Start: config.Time{Layout: "2006-01-02 15:04"}}
+//
+// A time parsed with a Layout lacking a time zone is in Location, if set,
+// or UTC.
+type Time struct {
+	time.Time
+	Layout   string
+	Location *time.Location
+	set      bool
+}
+
+type errTimeInvalid struct{ value, layout string }
+
+func (e errTimeInvalid) Error() string {
+	return fmt.Sprintf("Invalid time '%s': should be in the format '%s'",
+		e.value, e.layout)
+}
+
+func (t Time) layout() string {
+	if t.Layout != "" {
+		return t.Layout
+	}
+	return time.RFC3339
+}
+
+// Set satisfies the flag.Value interface. The empty string leaves the
+// Time unset.
+func (t *Time) Set(s string) error {
+	if s == "" {
+		t.Time, t.set = time.Time{}, false
+		return nil
+	}
+	loc := t.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+	v, err := time.ParseInLocation(t.layout(), s, loc)
+	if err != nil {
+		return errTimeInvalid{s, t.layout()}
+	}
+	t.Time = v
+	t.set = true
+	return nil
+}
+
+// String satisfies the flag.Value interface.
+func (t Time) String() string {
+	if !t.set && t.Time.IsZero() {
+		return ""
+	}
+	return t.Format(t.layout())
+}
+
+// IsSet returns true if the Time was assigned a value with Set or by
+// unmarshaling.
+func (t Time) IsSet() bool {
+	return t.set
+}
+
+// MarshalJSON satisfies the json.Marshaler interface
+func (t Time) MarshalJSON() ([]byte, error) {
+	return json.Marshal(t.String())
+}
+
+// MarshalYAML satisfies the yaml.Marshaler interface
+func (t Time) MarshalYAML() (interface{}, error) {
+	return t.String(), nil
+}
+
+// UnmarshalJSON satisfies the json.Unmarshaler interface
+func (t *Time) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	return t.Set(s)
+}
+
+// UnmarshalYAML satisfies the yaml.Unmarshaler interface
+func (t *Time) UnmarshalYAML(u func(interface{}) error) error {
+	var s string
+	if err := u(&s); err != nil {
+		return err
+	}
+	return t.Set(s)
+}
+
+// TimeOfDay is a wall clock time, written as "HH:MM" or "HH:MM:SS" in 24
+// hour format, e.g., "02:30" or "23:59:59".
+type TimeOfDay struct {
+	Hour, Minute, Second int
+	set                  bool
+}
+
+type errTimeOfDayInvalid string
+
+func (e errTimeOfDayInvalid) Error() string {
+	return fmt.Sprintf("Invalid time of day '%s': should be HH:MM or HH:MM:SS",
+		string(e))
+}
+
+// Set satisfies the flag.Value interface. The empty string leaves the
+// TimeOfDay unset.
+func (t *TimeOfDay) Set(s string) error {
+	if s == "" {
+		*t = TimeOfDay{}
+		return nil
+	}
+	parts := strings.Split(s, ":")
+	if len(parts) < 2 || len(parts) > 3 {
+		return errTimeOfDayInvalid(s)
+	}
+	var v [3]int
+	limits := [3]int{23, 59, 59}
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil || len(p) > 2 || n < 0 || n > limits[i] {
+			return errTimeOfDayInvalid(s)
+		}
+		v[i] = n
+	}
+	*t = TimeOfDay{Hour: v[0], Minute: v[1], Second: v[2], set: true}
+	return nil
+}
+
+// String satisfies the flag.Value interface. The seconds are omitted if
+// zero, and an unset TimeOfDay is the empty string.
+func (t TimeOfDay) String() string {
+	if !t.set {
+		return ""
+	}
+	if t.Second != 0 {
+		return fmt.Sprintf("%02d:%02d:%02d", t.Hour, t.Minute, t.Second)
+	}
+	return fmt.Sprintf("%02d:%02d", t.Hour, t.Minute)
+}
+
+// IsSet returns true if the TimeOfDay was assigned a value with Set or by
+// unmarshaling.
+func (t TimeOfDay) IsSet() bool {
+	return t.set
+}
+
+// Duration returns the time elapsed since midnight at the TimeOfDay on a
+// day without daylight saving time transitions.
+func (t TimeOfDay) Duration() time.Duration {
+	return time.Duration(t.Hour)*time.Hour +
+		time.Duration(t.Minute)*time.Minute +
+		time.Duration(t.Second)*time.Second
+}
+
+// On returns the time at the TimeOfDay on the date of d, in d's location.
+func (t TimeOfDay) On(d time.Time) time.Time {
+	y, m, day := d.Date()
+	return time.Date(y, m, day, t.Hour, t.Minute, t.Second, 0, d.Location())
+}
+
+// Next returns the first time at the TimeOfDay after d, in d's location.
+func (t TimeOfDay) Next(d time.Time) time.Time {
+	n := t.On(d)
+	if !n.After(d) {
+		n = t.On(d.AddDate(0, 0, 1))
+	}
+	return n
+}
+
+// MarshalJSON satisfies the json.Marshaler interface
+func (t TimeOfDay) MarshalJSON() ([]byte, error) {
+	return json.Marshal(t.String())
+}
+
+// MarshalYAML satisfies the yaml.Marshaler interface
+func (t TimeOfDay) MarshalYAML() (interface{}, error) {
+	return t.String(), nil
+}
+
+// UnmarshalJSON satisfies the json.Unmarshaler interface
+func (t *TimeOfDay) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	return t.Set(s)
+}
+
+// UnmarshalYAML satisfies the yaml.Unmarshaler interface
+func (t *TimeOfDay) UnmarshalYAML(u func(interface{}) error) error {
+	var s string
+	if err := u(&s); err != nil {
+		return err
+	}
+	return t.Set(s)
+}
+
+// Location provides JSON, YAML and flag support for time zones, given as
+// IANA time zone names, e.g., "America/New_York", or "UTC" or "Local",
+// and loaded with time.LoadLocation. The empty string leaves the Location
+// unset, rather than selecting UTC as time.LoadLocation does.
+type Location struct{ *time.Location }
+
+// Set satisfies the flag.Value interface.
+func (l *Location) Set(s string) error {
+	if s == "" {
+		l.Location = nil
+		return nil
+	}
+	loc, err := time.LoadLocation(s)
+	if err != nil {
+		return err
+	}
+	l.Location = loc
+	return nil
+}
+
+// String satisfies the flag.Value interface.
+func (l Location) String() string {
+	if l.Location == nil {
+		return ""
+	}
+	return l.Location.String()
+}
+
+// IsSet returns true if the Location was assigned a value with Set or by
+// unmarshaling.
+func (l Location) IsSet() bool {
+	return l.Location != nil
+}
+
+// MarshalJSON satisfies the json.Marshaler interface
+func (l Location) MarshalJSON() ([]byte, error) {
+	return json.Marshal(l.String())
+}
+
+// MarshalYAML satisfies the yaml.Marshaler interface
+func (l Location) MarshalYAML() (interface{}, error) {
+	return l.String(), nil
+}
+
+// UnmarshalJSON satisfies the json.Unmarshaler interface
+func (l *Location) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	return l.Set(s)
+}
+
+// UnmarshalYAML satisfies the yaml.Unmarshaler interface
+func (l *Location) UnmarshalYAML(u func(interface{}) error) error {
+	var s string
+	if err := u(&s); err != nil {
+		return err
+	}
+	return l.Set(s)
+}
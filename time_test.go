@@ -0,0 +1,69 @@
+/*
+ * Copyright 2018 Farsight Security, Inc.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package config
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestTime(t *testing.T) {
+	var v Time
+	checkOK(t, nil, !v.IsSet() && v.String() == "")
+	checkOK(t, v.Set("2018-06-01T12:00:00+02:00"), v.IsSet() &&
+		v.Equal(time.Date(2018, 6, 1, 10, 0, 0, 0, time.UTC)))
+	checkErr(t, v.Set("2018-06-01 12:00"))
+
+	est := time.FixedZone("EST", -5*3600)
+	l := Time{Layout: "2006-01-02 15:04", Location: est}
+	checkOK(t, l.Set("2018-06-01 12:00"), l.Equal(time.Date(2018, 6, 1, 17, 0, 0, 0, time.UTC)))
+	checkOK(t, nil, l.String() == "2018-06-01 12:00")
+	err := l.Set("2018-06-01")
+	checkOK(t, nil, err != nil && err.Error() ==
+		"Invalid time '2018-06-01': should be in the format '2006-01-02 15:04'")
+
+	var c struct{ Start Time }
+	c.Start.Layout = "2006-01-02"
+	checkOK(t, json.Unmarshal([]byte(`{"Start":"2018-06-01"}`), &c), c.Start.Day() == 1)
+	checkJSON(t, Time{}, `""`)
+	checkOK(t, c.Start.Set(""), !c.Start.IsSet())
+}
+
+func TestTimeOfDay(t *testing.T) {
+	var v TimeOfDay
+	checkOK(t, v.Set("02:30"), v.Hour == 2 && v.Minute == 30 && v.String() == "02:30")
+	checkOK(t, v.Set("23:59:59"), v.Duration() == 24*time.Hour-time.Second)
+	for _, s := range []string{"2", "24:00", "12:60", "12:00:60", "1:2:3:4", "-1:00", "012:00"} {
+		checkErr(t, v.Set(s))
+	}
+	checkJSON(t, TimeOfDay{Hour: 9, Minute: 5, set: true}, `"09:05"`)
+	checkYAML(t, TimeOfDay{Hour: 9, Minute: 5, Second: 1, set: true}, `"09:05:01"`)
+	checkJSON(t, TimeOfDay{}, `""`)
+	checkOK(t, v.Set(""), !v.IsSet())
+
+	checkOK(t, v.Set("02:30"), true)
+	d := time.Date(2018, 6, 1, 12, 0, 0, 0, time.UTC)
+	checkOK(t, nil, v.Next(d).Equal(time.Date(2018, 6, 2, 2, 30, 0, 0, time.UTC)))
+	d = time.Date(2018, 6, 1, 1, 0, 0, 0, time.UTC)
+	checkOK(t, nil, v.Next(d).Equal(time.Date(2018, 6, 1, 2, 30, 0, 0, time.UTC)))
+}
+
+func TestLocation(t *testing.T) {
+	var l Location
+	checkOK(t, l.Set("UTC"), l.IsSet() && l.Location == time.UTC)
+	checkOK(t, l.Set(""), !l.IsSet())
+	checkErr(t, l.Set("Not/A_Zone"))
+	checkJSON(t, Location{time.UTC}, `"UTC"`)
+	checkJSON(t, Location{}, `""`)
+	checkYAML(t, Location{}, `""`)
+
+	l = Location{time.UTC}
+	checkOK(t, json.Unmarshal([]byte(`""`), &l), !l.IsSet())
+}
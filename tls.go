@@ -12,13 +12,11 @@ import (
 	"crypto/tls"
 	"crypto/x509"
 	"encoding/json"
-	"fmt"
 	"io/ioutil"
-	"strings"
 )
 
 // TLSClientAuth provides a convenience wrapper for tls.ClientAuthType and
-// conversion to and from string format.
+// conversion to and from string format, using Enum.
 //
 // Supported string values are:
 //	"none":           tls.NoClientCert  (default)
@@ -28,81 +26,81 @@ import (
 //	"require+verify": tls.RequireAndVerifyClientCert
 type TLSClientAuth struct{ tls.ClientAuthType }
 
-var clientAuthTypes = map[string]tls.ClientAuthType{
-	"none":           tls.NoClientCert,
-	"request":        tls.RequestClientCert,
-	"require":        tls.RequireAnyClientCert,
-	"verify":         tls.VerifyClientCertIfGiven,
-	"require+verify": tls.RequireAndVerifyClientCert,
-}
-
-// String satisfies the flag.Value interface
-func (auth *TLSClientAuth) String() string {
-	for name, typ := range clientAuthTypes {
-		if typ == auth.ClientAuthType {
-			return name
-		}
+func init() {
+	if err := RegisterEnum(
+		EnumName[tls.ClientAuthType]{"none", tls.NoClientCert},
+		EnumName[tls.ClientAuthType]{"request", tls.RequestClientCert},
+		EnumName[tls.ClientAuthType]{"require", tls.RequireAnyClientCert},
+		EnumName[tls.ClientAuthType]{"verify", tls.VerifyClientCertIfGiven},
+		EnumName[tls.ClientAuthType]{"require+verify", tls.RequireAndVerifyClientCert},
+	); err != nil {
+		panic(err)
 	}
-	return ""
 }
 
-type invalidClientAuthType string
-
-func (i invalidClientAuthType) Error() string {
-	return fmt.Sprintf(`Invalid ClientAuthType "%s".`, i)
+func (auth TLSClientAuth) enum() Enum[tls.ClientAuthType] {
+	return Enum[tls.ClientAuthType]{Value: auth.ClientAuthType, set: auth.IsSet()}
 }
 
-type invalidClientAuthTypeValue tls.ClientAuthType
+func (auth *TLSClientAuth) setEnum(e Enum[tls.ClientAuthType]) {
+	auth.ClientAuthType = e.Value
+}
 
-func (i invalidClientAuthTypeValue) Error() string {
-	return fmt.Sprintf("Invalid ClientAuthType value %v", i)
+// String satisfies the flag.Value interface
+func (auth *TLSClientAuth) String() string {
+	return auth.enum().String()
 }
 
 // Set satisfies the flag.Value interface.
 func (auth *TLSClientAuth) Set(s string) error {
-	if a, ok := clientAuthTypes[s]; ok {
-		auth.ClientAuthType = a
-		return nil
+	var e Enum[tls.ClientAuthType]
+	if err := e.Set(s); err != nil {
+		return err
 	}
-	return invalidClientAuthType(s)
+	auth.setEnum(e)
+	return nil
 }
 
 // MarshalJSON satisfies the json.Marshaler interface
 func (auth TLSClientAuth) MarshalJSON() ([]byte, error) {
-	s := auth.String()
-	if s == "" {
-		return nil, invalidClientAuthTypeValue(auth.ClientAuthType)
-	}
-	return json.Marshal(s)
+	return auth.enum().MarshalJSON()
 }
 
 // MarshalYAML satisfies the yaml.Marshaler interface
 func (auth TLSClientAuth) MarshalYAML() (interface{}, error) {
-	s := auth.String()
-	if s == "" {
-		return nil, invalidClientAuthTypeValue(auth.ClientAuthType)
-	}
-	return s, nil
+	return auth.enum().MarshalYAML()
 }
 
 // UnmarshalJSON satisfies the json.Unmarshaler interface
 func (auth *TLSClientAuth) UnmarshalJSON(b []byte) error {
-	var s string
-	if err := json.Unmarshal(b, &s); err != nil {
+	var e Enum[tls.ClientAuthType]
+	if err := e.UnmarshalJSON(b); err != nil {
 		return err
 	}
-	return auth.Set(strings.ToLower(s))
+	auth.setEnum(e)
+	return nil
 }
 
 // UnmarshalYAML satisfies the yaml.Unmarshaler interface
 func (auth *TLSClientAuth) UnmarshalYAML(u func(interface{}) error) error {
-	var s string
-	if err := u(&s); err != nil {
+	var e Enum[tls.ClientAuthType]
+	if err := e.UnmarshalYAML(u); err != nil {
 		return err
 	}
-	return auth.Set(strings.ToLower(s))
+	auth.setEnum(e)
+	return nil
+}
+
+// IsSet returns true if the TLSClientAuth requests client certificates,
+// i.e., is other than "none". An explicit "none" cannot be told apart from
+// an absent setting, so a TLSClientAuth cannot be tagged
+// `config:"required"`; use Optional[TLSClientAuth] instead.
+func (auth TLSClientAuth) IsSet() bool {
+	return auth.ClientAuthType != tls.NoClientCert
 }
 
+func (TLSClientAuth) zeroIsUnset() {}
+
 // TLSConfig contains the configuration for TLS as it appears on the JSON
 // or YAML config. Values parsed from the config are translated and loaded
 // into corresponding fields in tls.Config.
@@ -155,6 +153,11 @@ func (t *TLS) UnmarshalYAML(u func(interface{}) error) (err error) {
 	return
 }
 
+// IsSet returns true if the TLS configuration was loaded by unmarshaling.
+func (t TLS) IsSet() bool {
+	return t.Config != nil
+}
+
 func loadTLSConfig(jc TLSConfig) (*tls.Config, error) {
 	var err error
 	tc := new(tls.Config)
@@ -10,20 +10,194 @@ package config
 
 import (
 	"encoding/json"
+	"fmt"
+	"net"
 	"net/url"
+	"strings"
 )
 
 // URL provides JSON Marshaling and Unmarshaling of URLs, internally
 // representing them as *url.URL from net/url.
+//
+// A URL accepts anything url.Parse accepts other than the empty string,
+// which leaves the URL unset. An unset URL marshals as the empty string.
+// Redacted returns the URL with any password replaced, for logging. A
+// CheckedURL constrains the URLs accepted.
 type URL struct{ *url.URL }
 
-// Set satisfies the flag.Value interface along with the net.URL String()
-// method.
+// Set satisfies the flag.Value interface along with the String() method.
 func (u *URL) Set(s string) (err error) {
+	if s == "" {
+		u.URL = nil
+		return nil
+	}
 	u.URL, err = url.Parse(s)
 	return
 }
 
+// String returns the URL as a string, or the empty string if it is unset.
+func (u URL) String() string {
+	if u.URL == nil {
+		return ""
+	}
+	return u.URL.String()
+}
+
+// Redacted returns the URL as a string with any password replaced by
+// "xxxxx", or the empty string if it is unset.
+func (u URL) Redacted() string {
+	if u.URL == nil {
+		return ""
+	}
+	return u.URL.Redacted()
+}
+
+// URLPolicy constrains the URLs accepted for a configuration field. It is
+// usually given as the Policy of a CheckedURL, or may be applied to a URL
+// directly with Apply.
+//
+// If Base is set, relative references are resolved against it before the
+// other constraints are checked. DefaultPorts gives the port added to a
+// URL of the given scheme which has a host but no port.
+type URLPolicy struct {
+	RequireAbsolute bool
+	Schemes         []string
+	RequireUser     bool
+	ForbidUser      bool
+	DefaultPorts    map[string]string
+	Base            *url.URL
+}
+
+type errURLInvalid struct{ url, reason string }
+
+func (e errURLInvalid) Error() string {
+	return fmt.Sprintf("Invalid URL '%s': %s", e.url, e.reason)
+}
+
+// Parse parses s as a URL, applying the policy as with Apply.
+func (p URLPolicy) Parse(s string) (URL, error) {
+	var u URL
+	if err := u.Set(s); err != nil {
+		return URL{}, err
+	}
+	if err := p.Apply(&u); err != nil {
+		return URL{}, err
+	}
+	return u, nil
+}
+
+// Apply resolves u against the policy's Base, checks it against the
+// policy's constraints, and adds a default port. Apply does nothing if u
+// is unset.
+func (p URLPolicy) Apply(u *URL) error {
+	if u.URL == nil {
+		return nil
+	}
+	v := u.URL
+	if p.Base != nil {
+		v = p.Base.ResolveReference(v)
+	}
+	if err := p.check(u.Redacted(), v); err != nil {
+		return err
+	}
+	if port, ok := p.DefaultPorts[v.Scheme]; ok && v.Host != "" && v.Port() == "" {
+		if v == u.URL {
+			c := *v
+			v = &c
+		}
+		v.Host = net.JoinHostPort(v.Hostname(), port)
+	}
+	u.URL = v
+	return nil
+}
+
+func (p URLPolicy) check(s string, v *url.URL) error {
+	if p.RequireAbsolute && !v.IsAbs() {
+		return errURLInvalid{s, "not an absolute URL"}
+	}
+	if len(p.Schemes) > 0 {
+		ok := false
+		for _, scheme := range p.Schemes {
+			if strings.EqualFold(scheme, v.Scheme) {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			return errURLInvalid{s, fmt.Sprintf("scheme must be one of %s",
+				strings.Join(p.Schemes, ", "))}
+		}
+	}
+	if p.RequireUser && v.User == nil {
+		return errURLInvalid{s, "user info required"}
+	}
+	if p.ForbidUser && v.User != nil {
+		return errURLInvalid{s, "user info not allowed"}
+	}
+	return nil
+}
+
+// IsSet returns true if the URL was assigned a value with Set or by
+// unmarshaling.
+func (u URL) IsSet() bool {
+	return u.URL != nil
+}
+
+// CheckedURL is a URL constrained by a URLPolicy, which is set before Set
+// or unmarshaling, e.g.:
+//
+//      cfg := Config{
+//              Server: config.CheckedURL{Policy: config.URLPolicy{
+//                      RequireAbsolute: true,
+//                      Schemes:         []string{"https"},
+//                      DefaultPorts:    map[string]string{"https": "8443"},
+//              }},
+//      }
+//
+// Set and unmarshaling apply the policy to the URL given, and Validate,
+// LoadYAML and LoadJSON apply it to a CheckedURL which was assigned
+// directly.
+type CheckedURL struct {
+	URL
+	Policy URLPolicy
+}
+
+// Set satisfies the flag.Value interface, parsing s and applying the
+// Policy. The empty string leaves the CheckedURL unset.
+func (u *CheckedURL) Set(s string) error {
+	var v URL
+	if err := v.Set(s); err != nil {
+		return err
+	}
+	if err := u.Policy.Apply(&v); err != nil {
+		return err
+	}
+	u.URL = v
+	return nil
+}
+
+func (u *CheckedURL) checkLoaded(dir string) error {
+	return u.Policy.Apply(&u.URL)
+}
+
+// UnmarshalJSON satisfies json.Unmarshaler
+func (u *CheckedURL) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	return u.Set(s)
+}
+
+// UnmarshalYAML satisfies yaml.Unmarshaler
+func (u *CheckedURL) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var s string
+	if err := unmarshal(&s); err != nil {
+		return err
+	}
+	return u.Set(s)
+}
+
 // UnmarshalJSON satisfies json.Unmarshaler
 func (u *URL) UnmarshalJSON(b []byte) error {
 	var s string
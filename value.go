@@ -0,0 +1,88 @@
+/*
+ * Copyright 2018 Farsight Security, Inc.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package config
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+	"strconv"
+)
+
+// A Value can be converted from a string. It is the subset of flag.Value
+// satisfied by pointers to all of the value types in this package.
+type Value interface {
+	Set(string) error
+}
+
+type errUnsupportedType struct{ reflect.Type }
+
+func (e errUnsupportedType) Error() string {
+	return fmt.Sprintf("Unsupported config value type %s", e.Type)
+}
+
+// setValue parses s into the value pointed to by p, using its Set or
+// UnmarshalText method if it has one, or strconv for primitive types.
+func setValue(p interface{}, s string) error {
+	switch v := p.(type) {
+	case Value:
+		return v.Set(s)
+	case encoding.TextUnmarshaler:
+		return v.UnmarshalText([]byte(s))
+	}
+
+	rv := reflect.ValueOf(p).Elem()
+	switch rv.Kind() {
+	case reflect.String:
+		rv.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		rv.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(s, 0, rv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		rv.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(s, 0, rv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		rv.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, rv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		rv.SetFloat(f)
+	default:
+		return errUnsupportedType{rv.Type()}
+	}
+	return nil
+}
+
+// formatValue returns the string form of the value pointed to by p, using
+// its String or MarshalText method if it has one.
+func formatValue(p interface{}) string {
+	switch v := p.(type) {
+	case fmt.Stringer:
+		return v.String()
+	case encoding.TextMarshaler:
+		b, err := v.MarshalText()
+		if err != nil {
+			return ""
+		}
+		return string(b)
+	}
+	return fmt.Sprint(reflect.ValueOf(p).Elem().Interface())
+}